@@ -0,0 +1,599 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipfs-api"
+	"github.com/stefanhans/go-ipfs-play/commander"
+)
+
+// The address of the API of the IPFS daemon, configurable like all flags
+var apiAddress = flag.String("api", "localhost:5001", "address of the API of the IPFS daemon")
+
+// A command of the IPFS API as returned by the 'commands' request
+type ipfsCommandTree struct {
+	Name        string
+	Subcommands []ipfsCommandTree
+	Options     []struct {
+		Names []string
+	}
+}
+
+// A command of the IPFS API with the names of its options
+type ipfsCommand struct {
+	Command string   `json:"command"`
+	Options []string `json:"options"`
+}
+
+func (c ipfsCommand) String() string {
+	return c.Command
+}
+
+// Append the command and its subcommands with their full names to the list
+func flattenCommands(list []ipfsCommand, prefix string, tree ipfsCommandTree) []ipfsCommand {
+
+	command := ipfsCommand{Command: strings.TrimSpace(prefix + " " + tree.Name), Options: []string{}}
+	for _, option := range tree.Options {
+		command.Options = append(command.Options, strings.Join(option.Names, "|"))
+	}
+	list = append(list, command)
+
+	for _, subcommand := range tree.Subcommands {
+		list = flattenCommands(list, command.Command, subcommand)
+	}
+	return list
+}
+
+func ipfsCommands(ctx context.Context, arguments []string) (interface{}, error) {
+
+	sh := shell.NewShell(*apiAddress)
+
+	var tree ipfsCommandTree
+	err := sh.Request("commands", "flags=true").Exec(ctx, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("commands.Exec(): %v", err)
+	}
+
+	return flattenCommands(nil, "", tree), nil
+}
+
+// A file added with its CID
+type addedFile struct {
+	CID  string `json:"cid"`
+	File string `json:"file"`
+}
+
+func (f addedFile) String() string {
+	return fmt.Sprintf("added %s %s", f.CID, f.File)
+}
+
+func addFiles(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("no file to add specified")
+	}
+
+	sh := shell.NewShell(*apiAddress)
+
+	var added []addedFile
+	for _, filename := range arguments {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("os.Open: %v", err)
+		}
+		var cid string
+		err = callWithContext(ctx, func() error {
+			var err error
+			cid, err = sh.Add(file)
+			return err
+		})
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("sh.Add(): %v", err)
+		}
+		added = append(added, addedFile{CID: cid, File: filename})
+		commander.SetResult(ctx, cid)
+	}
+	return added, nil
+}
+
+func catPath(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) != 1 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'cat path'")
+	}
+
+	sh := shell.NewShell(*apiAddress)
+
+	response, err := sh.Request("cat", arguments[0]).Send(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cat.Send(): %v", err)
+	}
+	defer response.Close()
+	if response.Error != nil {
+		return nil, fmt.Errorf("cat.Send(): %v", response.Error)
+	}
+
+	// The content is no structured result, but copied as it is
+	_, err = io.Copy(commander.Output(ctx), response.Output)
+	if err != nil {
+		return nil, fmt.Errorf("io.Copy(): %v", err)
+	}
+	return nil, nil
+}
+
+// A pinned CID with the type of the pin
+type pinInfo struct {
+	CID  string `json:"cid"`
+	Type string `json:"type"`
+}
+
+func (p pinInfo) String() string {
+	return fmt.Sprintf("%s %s", p.CID, p.Type)
+}
+
+func pinCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 ||
+		(arguments[0] != "ls" && len(arguments) != 2) {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'pin (add|rm <path>)|ls'")
+	}
+
+	sh := shell.NewShell(*apiAddress)
+
+	switch arguments[0] {
+	case "add":
+		err := sh.Request("pin/add", arguments[1]).Option("recursive", true).Exec(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("pin/add.Exec(): %v", err)
+		}
+		commander.SetResult(ctx, arguments[1])
+
+	case "rm":
+		err := sh.Request("pin/rm", arguments[1]).Option("recursive", true).Exec(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("pin/rm.Exec(): %v", err)
+		}
+		commander.SetResult(ctx, arguments[1])
+
+	case "ls":
+		var pins struct{ Keys map[string]shell.PinInfo }
+		err := sh.Request("pin/ls").Exec(ctx, &pins)
+		if err != nil {
+			return nil, fmt.Errorf("pin/ls.Exec(): %v", err)
+		}
+		list := []pinInfo{}
+		for cid, info := range pins.Keys {
+			list = append(list, pinInfo{CID: cid, Type: info.Type})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].CID < list[j].CID })
+		return list, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %q. Usage: \n\t 'pin (add|rm <path>)|ls'", arguments[0])
+	}
+	return nil, nil
+}
+
+// An entry of a directory of the MFS
+type filesEntry struct {
+	Hash string `json:"hash"`
+	Name string `json:"name"`
+}
+
+func (e filesEntry) String() string {
+	return fmt.Sprintf("%s %s", e.Hash, e.Name)
+}
+
+// The status of a file or directory of the MFS
+type filesStat struct {
+	Hash           string `json:"hash"`
+	Type           string `json:"type"`
+	CumulativeSize uint64 `json:"cumulativeSize"`
+}
+
+func (s filesStat) String() string {
+	return fmt.Sprintf("%s %s %d", s.Hash, s.Type, s.CumulativeSize)
+}
+
+// The current directory of the MFS, which relative paths of 'files' start from
+var (
+	mfsCwd      = "/"
+	mfsCwdMutex sync.Mutex
+)
+
+// Return the current directory of the MFS
+func getMfsCwd() string {
+
+	mfsCwdMutex.Lock()
+	defer mfsCwdMutex.Unlock()
+
+	return mfsCwd
+}
+
+// Return the MFS path relative to the current directory as absolute one
+func resolveMfsPath(mfsPath string) string {
+	if path.IsAbs(mfsPath) {
+		return path.Clean(mfsPath)
+	}
+	return path.Join(getMfsCwd(), mfsPath)
+}
+
+// Return the source of 'files cp' as absolute path, i.e. IPFS paths unchanged, CIDs as IPFS paths and MFS paths
+// resolved
+func resolveFilesSource(source string) string {
+
+	if strings.HasPrefix(source, "/ipfs/") {
+		return source
+	}
+	if _, err := cid.Decode(strings.SplitN(source, "/", 2)[0]); err == nil {
+		return "/ipfs/" + source
+	}
+	return resolveMfsPath(source)
+}
+
+func filesCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 ||
+		(arguments[0] != "ls" && arguments[0] != "cd" && arguments[0] != "pwd" && len(arguments) < 2) {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'files (ls|cd [<path>])|pwd|(mkdir|rm|stat <path>)|(cp <source> <path>)'")
+	}
+
+	sh := shell.NewShell(*apiAddress)
+
+	switch arguments[0] {
+	case "ls":
+		mfsPath := getMfsCwd()
+		if len(arguments) > 1 {
+			mfsPath = resolveMfsPath(arguments[1])
+		}
+		entries, err := sh.FilesLs(ctx, mfsPath, shell.FilesLs.Stat(true))
+		if err != nil {
+			return nil, fmt.Errorf("sh.FilesLs(): %v", err)
+		}
+		list := []filesEntry{}
+		for _, entry := range entries {
+			name := entry.Name
+			if entry.Type == mfsDirectory {
+				name += "/"
+			}
+			list = append(list, filesEntry{Hash: entry.Hash, Name: name})
+		}
+		return list, nil
+
+	case "cd":
+		mfsPath := "/"
+		if len(arguments) > 1 {
+			mfsPath = resolveMfsPath(arguments[1])
+		}
+		stat, err := sh.FilesStat(ctx, mfsPath)
+		if err != nil {
+			return nil, fmt.Errorf("sh.FilesStat(): %v", err)
+		}
+		if stat.Type != "directory" {
+			return nil, fmt.Errorf("%s is no directory", mfsPath)
+		}
+		mfsCwdMutex.Lock()
+		mfsCwd = mfsPath
+		mfsCwdMutex.Unlock()
+		commander.SetResult(ctx, mfsPath)
+
+	case "pwd":
+		return getMfsCwd(), nil
+
+	case "mkdir":
+		err := sh.FilesMkdir(ctx, resolveMfsPath(arguments[1]), shell.FilesMkdir.Parents(true))
+		if err != nil {
+			return nil, fmt.Errorf("sh.FilesMkdir(): %v", err)
+		}
+
+	case "rm":
+		err := sh.FilesRm(ctx, resolveMfsPath(arguments[1]), true)
+		if err != nil {
+			return nil, fmt.Errorf("sh.FilesRm(): %v", err)
+		}
+
+	case "stat":
+		stat, err := sh.FilesStat(ctx, resolveMfsPath(arguments[1]))
+		if err != nil {
+			return nil, fmt.Errorf("sh.FilesStat(): %v", err)
+		}
+		commander.SetResult(ctx, stat.Hash)
+		return filesStat{Hash: stat.Hash, Type: stat.Type, CumulativeSize: stat.CumulativeSize}, nil
+
+	case "cp":
+		if len(arguments) != 3 {
+			return nil, fmt.Errorf("wrong input. Usage: \n\t 'files cp <source> <path>'")
+		}
+		err := sh.FilesCp(ctx, resolveFilesSource(arguments[1]), resolveMfsPath(arguments[2]))
+		if err != nil {
+			return nil, fmt.Errorf("sh.FilesCp(): %v", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %q. Usage: \n\t 'files (ls|cd [<path>])|pwd|(mkdir|rm|stat <path>)|(cp <source> <path>)'", arguments[0])
+	}
+	return nil, nil
+}
+
+// A key of the node
+type keyInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (k keyInfo) String() string {
+	return fmt.Sprintf("%s %s", k.ID, k.Name)
+}
+
+func keyCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 ||
+		(arguments[0] != "ls" && len(arguments) != 2) {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'key ls|(gen|rm <name>)'")
+	}
+
+	sh := shell.NewShell(*apiAddress)
+
+	switch arguments[0] {
+	case "ls":
+		keys, err := sh.KeyList(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("sh.KeyList(): %v", err)
+		}
+		list := []keyInfo{}
+		for _, key := range keys {
+			list = append(list, keyInfo{ID: key.Id, Name: key.Name})
+		}
+		return list, nil
+
+	case "gen":
+		key, err := sh.KeyGen(ctx, arguments[1])
+		if err != nil {
+			return nil, fmt.Errorf("sh.KeyGen(): %v", err)
+		}
+		commander.SetResult(ctx, key.Id)
+		return keyInfo{ID: key.Id, Name: key.Name}, nil
+
+	case "rm":
+		_, err := sh.KeyRm(ctx, arguments[1])
+		if err != nil {
+			return nil, fmt.Errorf("sh.KeyRm(): %v", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %q. Usage: \n\t 'key ls|(gen|rm <name>)'", arguments[0])
+	}
+	return nil, nil
+}
+
+// An IPFS path published to the IPNS name
+type publishedPath struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (p publishedPath) String() string {
+	return fmt.Sprintf("published %s to /ipns/%s", p.Value, p.Name)
+}
+
+func publishPath(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 || len(arguments) > 2 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'publish <path> [<key>]'")
+	}
+
+	key := "self"
+	if len(arguments) == 2 {
+		key = arguments[1]
+	}
+
+	sh := shell.NewShell(*apiAddress)
+
+	var response shell.PublishResponse
+	err := sh.Request("name/publish", arguments[0]).Option("resolve", true).Option("key", key).Exec(ctx, &response)
+	if err != nil {
+		return nil, fmt.Errorf("name/publish.Exec(): %v", err)
+	}
+	commander.SetResult(ctx, response.Name)
+	return publishedPath{Name: response.Name, Value: response.Value}, nil
+}
+
+// Call the IPFS API without support of contexts, but return as soon as the context is cancelled
+//
+// The call itself runs on in the background until the daemon responds.
+func callWithContext(ctx context.Context, call func() error) error {
+
+	done := make(chan error, 1)
+	go func() {
+		done <- call()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Return the short peer ID of the node, i.e. the first two and the last six characters, e.g. Qm*bWNqzm
+func peerField(ctx context.Context) (string, error) {
+
+	sh := shell.NewShell(*apiAddress)
+
+	var id *shell.IdOutput
+	err := callWithContext(ctx, func() error {
+		var err error
+		id, err = sh.ID()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("sh.ID(): %v", err)
+	}
+	if len(id.ID) <= 8 {
+		return id.ID, nil
+	}
+	return id.ID[:2] + "*" + id.ID[len(id.ID)-6:], nil
+}
+
+// Return the number of peers the node is connected to
+func peersField(ctx context.Context) (string, error) {
+
+	sh := shell.NewShell(*apiAddress)
+
+	peers, err := sh.SwarmPeers(ctx)
+	if err != nil {
+		return "", fmt.Errorf("sh.SwarmPeers(): %v", err)
+	}
+	return fmt.Sprintf("%d", len(peers.Peers)), nil
+}
+
+// Type of directories in the MFS listing
+const mfsDirectory = 1
+
+// Maximum time to request completions from the daemon, which completes nothing in time, if not responding
+const completionTimeout = time.Second
+
+// Return the pinned CIDs starting with the prefix
+func completePinned(prefix string) []string {
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	sh := shell.NewShell(*apiAddress)
+
+	var pins map[string]shell.PinInfo
+	err := callWithContext(ctx, func() error {
+		var err error
+		pins, err = sh.Pins()
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+	var cids []string
+	for cid := range pins {
+		cids = append(cids, cid)
+	}
+	return commander.CompleteWords(prefix, cids...)
+}
+
+// Return the MFS paths starting with the prefix, relative ones in the current directory, directories end with a slash
+func completeMfsPaths(prefix string) []string {
+
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i+1]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	sh := shell.NewShell(*apiAddress)
+
+	entries, err := sh.FilesLs(ctx, resolveMfsPath(dir), shell.FilesLs.Stat(true))
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		mfsPath := dir + entry.Name
+		if entry.Type == mfsDirectory {
+			mfsPath += "/"
+		}
+		paths = append(paths, mfsPath)
+	}
+	return commander.CompleteWords(prefix, paths...)
+}
+
+// Return the names of the keys starting with the prefix
+func completeKeys(prefix string) []string {
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	sh := shell.NewShell(*apiAddress)
+
+	keys, err := sh.KeyList(ctx)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, key := range keys {
+		names = append(names, key.Name)
+	}
+	return commander.CompleteWords(prefix, names...)
+}
+
+// Completer of commands with pinned CIDs as arguments
+func pinnedCompleter(arguments []string) []string {
+
+	if len(arguments) == 1 {
+		return completePinned(arguments[0])
+	}
+	return nil
+}
+
+// Completer of the pin command
+func pinCompleter(arguments []string) []string {
+
+	switch {
+	case len(arguments) == 1:
+		return commander.CompleteWords(arguments[0], "add", "rm", "ls")
+	case len(arguments) == 2 && arguments[0] == "rm":
+		return completePinned(arguments[1])
+	}
+	return nil
+}
+
+// Completer of the files command
+func filesCompleter(arguments []string) []string {
+
+	switch {
+	case len(arguments) == 1:
+		return commander.CompleteWords(arguments[0], "ls", "cd", "pwd", "mkdir", "rm", "stat", "cp")
+	case len(arguments) == 2 && arguments[0] == "cp":
+		if strings.HasPrefix(arguments[1], "/ipfs/") {
+			return nil
+		}
+		return completeMfsPaths(arguments[1])
+	case len(arguments) == 2 || (len(arguments) == 3 && arguments[0] == "cp"):
+		return completeMfsPaths(arguments[len(arguments)-1])
+	}
+	return nil
+}
+
+// Completer of the key command
+func keyCompleter(arguments []string) []string {
+
+	switch {
+	case len(arguments) == 1:
+		return commander.CompleteWords(arguments[0], "ls", "gen", "rm")
+	case len(arguments) == 2 && arguments[0] == "rm":
+		return completeKeys(arguments[1])
+	}
+	return nil
+}
+
+// Completer of the publish command
+func publishCompleter(arguments []string) []string {
+
+	switch len(arguments) {
+	case 1:
+		return completePinned(arguments[0])
+	case 2:
+		return completeKeys(arguments[1])
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestResolveMfsPath(t *testing.T) {
+
+	mfsCwdMutex.Lock()
+	mfsCwd = "/docs"
+	mfsCwdMutex.Unlock()
+	defer func() {
+		mfsCwdMutex.Lock()
+		mfsCwd = "/"
+		mfsCwdMutex.Unlock()
+	}()
+
+	tests := []struct {
+		source string
+		want   string
+		cp     string
+	}{
+		{"README.md", "/docs/README.md", "/docs/README.md"},
+		{"../a/./b", "/a/b", "/a/b"},
+		{"/x//y/", "/x/y", "/x/y"},
+		{"/ipfs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u", "/ipfs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u",
+			"/ipfs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u"},
+		{"QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u", "/docs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u",
+			"/ipfs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u"},
+		{"QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u/hello.txt",
+			"/docs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u/hello.txt",
+			"/ipfs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u/hello.txt"},
+	}
+
+	for _, tt := range tests {
+		if got := resolveMfsPath(tt.source); got != tt.want {
+			t.Errorf("resolveMfsPath(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+		if got := resolveFilesSource(tt.source); got != tt.cp {
+			t.Errorf("resolveFilesSource(%q) = %q, want %q", tt.source, got, tt.cp)
+		}
+	}
+}
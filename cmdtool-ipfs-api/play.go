@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ipfs/go-ipfs-api"
+	"github.com/stefanhans/go-ipfs-play/commander"
+)
+
+// A field of "unknown" JSON data with its type
+type playField struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func (f playField) String() string {
+	return fmt.Sprintf("%q is of type %s", f.Key, f.Type)
+}
+
+func play(ctx context.Context, arguments []string) (interface{}, error) {
+
+	// Get rid of warnings
+	_ = arguments
+
+	commander.Logs(ctx).Debug("CMD: play")
+
+	sh := shell.NewShell(*apiAddress)
+
+	var commands map[string]interface{}
+	err := sh.Request("commands", "flags=true").Exec(ctx, &commands)
+	if err != nil {
+		return nil, fmt.Errorf("commands.Exec(): %v", err)
+	}
+
+	// Manually read from "unknown" JSON data
+	var fields []playField
+	for k, v := range commands {
+		switch vv := v.(type) {
+		case string:
+			fields = append(fields, playField{Key: k, Type: "string", Value: vv})
+
+		case []interface{}:
+			fields = append(fields, playField{Key: k, Type: fmt.Sprintf("array of %d", len(vv)), Value: vv})
+
+		case map[string]interface{}:
+			fields = append(fields, playField{Key: k, Type: "map", Value: vv})
+
+		case nil:
+			fields = append(fields, playField{Key: k, Type: "not set"})
+
+		default:
+			fields = append(fields, playField{Key: k, Type: fmt.Sprintf("%T", v), Value: v})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+
+	//bootstrapServer, err := sh.BootstrapAddDefault()
+	//if err != nil {
+	//	fmt.Printf("BootstrapAddDefault(): %v\n", err)
+	//}
+	//for i, b := range bootstrapServer {
+	//
+	//	fmt.Printf("bootstrapServer %d: %v\n", i, b)
+	//}
+
+	//var listOutput shell.PeersList
+	//err = sh.Request("bootstrap/list").Exec(context.Background(), &listOutput)
+	//if err != nil {
+	//	fmt.Printf("bootstrap/list.Exec(): %v\n", err)
+	//}
+	//fmt.Printf("listOutput: %v\n", listOutput)
+
+	//for i, b := range listOutput.Peers {
+	//
+	//	fmt.Printf("listOutput.Peers %d: %v\n", i, b)
+	//}
+
+	return fields, nil
+}
@@ -0,0 +1,12 @@
+# Regression test of adding and reading a file, needs a running IPFS daemon, run by 'test tests/'
+
+add README.md
+expect '^added (Qm|baf)\S+ README.md$'
+set cid $_
+assert $cid != ""
+
+cat $cid
+expect '^# Using IPFS HTTP Client Library'
+
+pin ls
+expect $cid
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+
+	"github.com/stefanhans/go-ipfs-play/commander"
+)
+
+// Category of the commands of the IPFS API
+const categoryIPFS = "IPFS"
+
+// Register the commands of the tool in addition to the built-in ones of the commander
+func registerCommands(c *commander.Commander) {
+
+	// Shell Exec
+	c.Register(commander.Command{
+		Name:        "commands",
+		Category:    categoryIPFS,
+		Synopsis:    "commands",
+		Description: "Shows all commands of the IPFS API with their options.",
+		Handler:     ipfsCommands,
+	})
+
+	// Files
+	c.Register(commander.Command{
+		Name:        "add",
+		Category:    categoryIPFS,
+		Synopsis:    "add file...",
+		Description: "Adds the files and prints their CIDs.\n\nThe CID of the last file is the result.",
+		Examples:    []string{"add README.md", "add a.txt b.txt && pin ls"},
+		Handler:     addFiles,
+		Completer:   commander.FileCompleter,
+	})
+	c.Register(commander.Command{
+		Name:        "cat",
+		Category:    categoryIPFS,
+		Synopsis:    "cat path",
+		Description: "Prints the content of the IPFS path or CID.",
+		Examples:    []string{"add README.md && cat $_"},
+		Handler:     catPath,
+		Completer:   pinnedCompleter,
+	})
+	c.Register(commander.Command{
+		Name:     "pin",
+		Category: categoryIPFS,
+		Synopsis: "pin (add|rm <path>)|ls",
+		Description: "Pins or unpins the IPFS path or CID or lists the pinned CIDs.\n\n" +
+			"Pinned CIDs are kept by the garbage collection of the node.",
+		Examples:  []string{"pin add QmHash", "pin ls"},
+		Handler:   pinCommand,
+		Completer: pinCompleter,
+	})
+	c.Register(commander.Command{
+		Name:     "files",
+		Category: categoryIPFS,
+		Synopsis: "files (ls|cd [<path>])|pwd|(mkdir|rm|stat <path>)|(cp <source> <path>)",
+		Description: "Manages the mutable file system (MFS).\n\n" +
+			"Relative paths start from the current directory, which 'cd' changes, by default to the root, " +
+			"and 'pwd' shows. 'ls' lists the directory, by default the current one, 'mkdir' creates directories " +
+			"including their parents, 'rm' removes recursively, 'stat' shows the CID, type and size, and 'cp' copies " +
+			"an IPFS path or CID into the MFS.",
+		Examples:  []string{"files mkdir /docs", "files cd /docs", "files cp /ipfs/QmHash README.md", "files ls"},
+		Handler:   filesCommand,
+		Completer: filesCompleter,
+	})
+
+	// Keys
+	c.Register(commander.Command{
+		Name:        "key",
+		Category:    categoryIPFS,
+		Synopsis:    "key ls|(gen|rm <name>)",
+		Description: "Lists, generates or removes the keys of the node.",
+		Examples:    []string{"key gen blog", "key ls"},
+		Handler:     keyCommand,
+		Completer:   keyCompleter,
+	})
+	c.Register(commander.Command{
+		Name:     "publish",
+		Category: categoryIPFS,
+		Synopsis: "publish <path> [<key>]",
+		Description: "Publishes the IPFS path with the key, by default the one of the node.\n\n" +
+			"The result is the IPNS name the path is published under.",
+		Examples:  []string{"publish /ipfs/QmHash blog"},
+		Handler:   publishPath,
+		Completer: publishCompleter,
+	})
+
+	// Prompt fields
+	c.RegisterPromptField(commander.PromptField{
+		Name:        "api",
+		Description: "the address of the API of the IPFS daemon",
+		Value: func(ctx context.Context) (string, error) {
+			return *apiAddress, nil
+		},
+	})
+	c.RegisterPromptField(commander.PromptField{
+		Name:        "peer",
+		Description: "the short peer ID of the node, e.g. Qm*bWNqzm",
+		Value:       peerField,
+	})
+	c.RegisterPromptField(commander.PromptField{
+		Name:        "peers",
+		Description: "the number of peers the node is connected to",
+		Value:       peersField,
+	})
+	c.RegisterPromptField(commander.PromptField{
+		Name:        "cwd",
+		Description: "the current directory of the MFS, set by 'files cd'",
+		Value: func(ctx context.Context) (string, error) {
+			return getMfsCwd(), nil
+		},
+	})
+
+	// Developer
+	c.Register(commander.Command{
+		Name:        "play",
+		Category:    commander.CategoryDeveloper,
+		Synopsis:    "play",
+		Description: "Shows the fields of the response to the 'commands' request of the IPFS API with their types.",
+		Handler:     play,
+	})
+}
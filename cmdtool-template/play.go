@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+
+	"github.com/stefanhans/go-ipfs-play/commander"
+)
+
+func play(ctx context.Context, arguments []string) (interface{}, error) {
+
+	// Get rid of warnings
+	_ = arguments
+
+	commander.Logs(ctx).Debug("CMD: play")
+
+	return nil, nil
+}
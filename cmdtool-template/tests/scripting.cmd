@@ -0,0 +1,21 @@
+# Regression test of variables, functions and checks, run by 'test tests/'
+
+set name world
+echo hello $name
+expect ^hello world$
+assert $_ == "hello world"
+
+func greet
+	echo hello $1
+	echo bye $1
+end
+greet you
+expect ^hello you$
+expect ^bye you$
+
+sleep x
+assert $? == 1
+
+output json
+echo quoted
+expect '^"quoted"$'
@@ -0,0 +1,16 @@
+package main
+
+import "github.com/stefanhans/go-ipfs-play/commander"
+
+// Register the commands of the tool in addition to the built-in ones of the commander
+func registerCommands(c *commander.Commander) {
+
+	// Developer
+	c.Register(commander.Command{
+		Name:        "play",
+		Category:    commander.CategoryDeveloper,
+		Synopsis:    "play",
+		Description: "Runs the code of developers playing.",
+		Handler:     play,
+	})
+}
@@ -0,0 +1,241 @@
+package commander
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// References to parameters in the command line of an alias
+var parameterPattern = regexp.MustCompile(`\$([0-9#]|\{[0-9]+\})`)
+
+// Load the aliases of the former sessions of the tool and save them from now on
+//
+// Each tool has its own aliases, i.e. the ones of the commands of other tools are not mixed in.
+func (c *Commander) aliasesInit(tool string) error {
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("os.UserHomeDir: %v", err)
+	}
+	c.aliasFilename = filepath.Join(home, fmt.Sprintf(".%s_aliases", tool))
+
+	file, err := os.Open(c.aliasFilename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("os.Open: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) == 2 && isAliasName(strings.TrimSpace(parts[0])) {
+			c.aliases[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return scanner.Err()
+}
+
+// Save the aliases for later sessions
+func (c *Commander) saveAliases() error {
+
+	if len(c.aliasFilename) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	for _, name := range c.aliasNames() {
+		expansion, _ := c.lookupAlias(name)
+		sb.WriteString(fmt.Sprintf("%s = %s\n", name, expansion))
+	}
+	err := ioutil.WriteFile(c.aliasFilename, []byte(sb.String()), 0600)
+	if err != nil {
+		return fmt.Errorf("ioutil.WriteFile: %v", err)
+	}
+	return nil
+}
+
+// Return the names of the aliases in sorted order
+func (c *Commander) aliasNames() []string {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	var names []string
+	for name := range c.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the command line the alias expands to
+func (c *Commander) lookupAlias(name string) (string, bool) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	expansion, ok := c.aliases[name]
+	return expansion, ok
+}
+
+// Check for a valid alias name, i.e. a single word without quotes, escapes and '='
+func isAliasName(name string) bool {
+	return len(name) > 0 && !strings.ContainsAny(name, " \t\n'\"\\#$;&|=")
+}
+
+// Execute the command line, if it starts with an alias, and return whether it did and the status
+//
+// The alias is expanded to its command line. If it references parameters like '$1' or '$#', the arguments of the
+// alias are bound to them, otherwise they are appended. An alias is not expanded again within its own expansion,
+// i.e. it executes the command of the same name, if any, otherwise the recursion fails.
+func (c *Commander) executeAlias(ctx context.Context, commandline string, report func(err error)) (bool, bool) {
+
+	tokens, err := tokenize(commandline, nil)
+	if err != nil || len(tokens) == 0 || tokens[0].kind != tokenWord {
+		return false, false
+	}
+	name := tokens[0].value
+	expansion, found := c.lookupAlias(name)
+	if !found || commandline[tokens[0].pos:tokens[0].pos+len(name)] != name {
+		return false, false
+	}
+
+	e := executionOf(ctx)
+	for i, expanding := range e.expanding {
+		if expanding != name {
+			continue
+		}
+		if _, ok := c.lookupCommand(name); ok {
+			return false, false
+		}
+		cycle := append(append([]string{}, e.expanding[i:]...), name)
+		report(fmt.Errorf("alias recursion %s", strings.Join(cycle, " -> ")))
+		return true, false
+	}
+
+	e.expanding = append(e.expanding, name)
+	defer func() {
+		e.expanding = e.expanding[:len(e.expanding)-1]
+	}()
+
+	rest := ""
+	if len(tokens) > 1 {
+		rest = commandline[tokens[1].pos:]
+	}
+
+	if !parameterPattern.MatchString(expansion) {
+		return true, c.executeChain(ctx, expansion+" "+rest, report)
+	}
+
+	// Bind the arguments to the parameters
+	arguments, err := tokenize(rest, variableLookup(ctx))
+	if err != nil {
+		report(err)
+		return true, false
+	}
+	parameters := []string{name}
+	for _, t := range arguments {
+		parameters = append(parameters, t.value)
+	}
+	defer pushArguments(ctx, parameters)()
+
+	return true, c.executeChain(ctx, expansion, report)
+}
+
+// Aliases by name
+type aliasList map[string]string
+
+// Print the aliases in sorted order like they are defined
+func (l aliasList) String() string {
+
+	var names []string
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("alias %s = %s", name, l[name])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *Commander) aliasCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	// List all aliases
+	if len(arguments) == 0 {
+		c.stateMutex.Lock()
+		defer c.stateMutex.Unlock()
+
+		list := make(aliasList)
+		for name, expansion := range c.aliases {
+			list[name] = expansion
+		}
+		return list, nil
+	}
+
+	// Accept 'name = command line' as well as 'name=command line'
+	if i := strings.Index(arguments[0], "="); i > 0 {
+		arguments = append([]string{arguments[0][:i], "="}, append([]string{arguments[0][i+1:]}, arguments[1:]...)...)
+	}
+
+	// Show the alias
+	if len(arguments) == 1 {
+		expansion, ok := c.lookupAlias(arguments[0])
+		if !ok {
+			return nil, fmt.Errorf("%q is not an alias", arguments[0])
+		}
+		SetResult(ctx, expansion)
+		return aliasList{arguments[0]: expansion}, nil
+	}
+
+	if arguments[1] != "=" || !isAliasName(arguments[0]) {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'alias [name [= command line]]'")
+	}
+	expansion := strings.TrimSpace(strings.Join(arguments[2:], " "))
+	if len(expansion) == 0 {
+		return nil, fmt.Errorf("empty command line of alias %q", arguments[0])
+	}
+	if _, err := tokenize(expansion, nil); err != nil {
+		return nil, fmt.Errorf("invalid command line of alias %q: %v", arguments[0], err)
+	}
+
+	c.stateMutex.Lock()
+	c.aliases[arguments[0]] = expansion
+	c.stateMutex.Unlock()
+	SetResult(ctx, expansion)
+	return nil, c.saveAliases()
+}
+
+func (c *Commander) unaliasCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'unalias name...'")
+	}
+
+	for _, name := range arguments {
+		if _, ok := c.lookupAlias(name); !ok {
+			return nil, fmt.Errorf("%q is not an alias", name)
+		}
+		c.stateMutex.Lock()
+		delete(c.aliases, name)
+		c.stateMutex.Unlock()
+	}
+	return nil, c.saveAliases()
+}
+
+// Completer of commands with alias names as arguments
+func (c *Commander) aliasCompleter(arguments []string) []string {
+	return CompleteWords(arguments[len(arguments)-1], c.aliasNames()...)
+}
@@ -0,0 +1,293 @@
+package commander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of bytes of the output of a command kept for 'expect'
+const maxCapturedOutput = 1 << 20
+
+// A buffer keeping the first bytes written up to its capacity and counting the rest dropped
+type cappedBuffer struct {
+	bytes.Buffer
+	capacity int
+	dropped  int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+
+	if free := b.capacity - b.Len(); free < len(p) {
+		if free > 0 {
+			b.Buffer.Write(p[:free])
+		}
+		b.dropped += len(p) - free
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// Count a failed check of the session
+func (c *Commander) failCheck() {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	c.failedChecks++
+}
+
+// Count a failure of the session, which is not checked by the next command
+func (c *Commander) failUnchecked() {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	c.uncheckedFailures++
+}
+
+// Return the number of failed checks of the session
+func (c *Commander) getFailedChecks() int {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return c.failedChecks
+}
+
+// Return the number of failures of the session, which are not checked by the next command
+func (c *Commander) getUncheckedFailures() int {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return c.uncheckedFailures
+}
+
+func (c *Commander) expectCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'expect regex'")
+	}
+
+	pattern := strings.Join(arguments, " ")
+	re, err := regexp.Compile("(?m)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+	}
+
+	lastOutput := executionOf(ctx).lastOutput
+	if !re.MatchString(lastOutput) {
+		c.failCheck()
+		return nil, fmt.Errorf("expected %q in output %q", pattern, abbreviate(lastOutput, 200))
+	}
+	return nil, nil
+}
+
+func (c *Commander) assertCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	ok, err := evaluateAssertion(arguments)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		c.failCheck()
+		return nil, fmt.Errorf("assertion failed: %s", strings.Join(quoteWords(arguments), " "))
+	}
+	return nil, nil
+}
+
+// Evaluate 'value' or 'value operator value', optionally negated by a leading '!'
+//
+// A single value is true, unless it is empty, "0" or "false". '=~' and '!~' match a regular expression, '<', '<=',
+// '>' and '>=' compare numbers, '==' and '!=' compare strings.
+func evaluateAssertion(arguments []string) (bool, error) {
+
+	negate := len(arguments) > 0 && arguments[0] == "!"
+	if negate {
+		arguments = arguments[1:]
+	}
+
+	var ok bool
+	switch len(arguments) {
+	case 1:
+		ok = arguments[0] != "" && arguments[0] != "0" && arguments[0] != "false"
+
+	case 3:
+		left, operator, right := arguments[0], arguments[1], arguments[2]
+		switch operator {
+		case "==":
+			ok = left == right
+		case "!=":
+			ok = left != right
+
+		case "=~", "!~":
+			re, err := regexp.Compile(right)
+			if err != nil {
+				return false, fmt.Errorf("invalid regular expression %q: %v", right, err)
+			}
+			ok = re.MatchString(left) == (operator == "=~")
+
+		case "<", "<=", ">", ">=":
+			l, err := strconv.ParseFloat(left, 64)
+			if err != nil {
+				return false, fmt.Errorf("%q is not a number", left)
+			}
+			r, err := strconv.ParseFloat(right, 64)
+			if err != nil {
+				return false, fmt.Errorf("%q is not a number", right)
+			}
+			ok = map[string]bool{"<": l < r, "<=": l <= r, ">": l > r, ">=": l >= r}[operator]
+
+		default:
+			return false, fmt.Errorf("unknown operator %q, use ==, !=, =~, !~, <, <=, > or >=", operator)
+		}
+
+	default:
+		return false, fmt.Errorf("wrong input. Usage: \n\t 'assert [!] value [(==|!=|=~|!~|<|<=|>|>=) value]'")
+	}
+	return ok != negate, nil
+}
+
+// Return the words quoted, if they are empty or contain blanks
+func quoteWords(words []string) []string {
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if word == "" || strings.ContainsAny(word, " \t\n") {
+			word = strconv.Quote(word)
+		}
+		quoted[i] = word
+	}
+	return quoted
+}
+
+// Return the text shortened to the maximum length
+func abbreviate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	return text[:max] + "..."
+}
+
+// Return a new session for a test with the commands of the tool, i.e. all except the ones defined as functions
+func (c *Commander) newTestSession(out *bytes.Buffer) *Commander {
+
+	session := New(c.name, strings.NewReader(""), out)
+	session.outputFormat = c.getOutputFormat()
+	session.strictMode = c.strict()
+	session.logs = c.logs
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	for name, command := range c.commands {
+		if _, builtin := session.commands[name]; !builtin && !c.functions[name] {
+			session.Register(*command)
+		}
+	}
+	return session
+}
+
+// Return the test scripts of the arguments, i.e. the files and the '*.cmd' files of the directories
+func testScripts(arguments []string) ([]string, error) {
+
+	var filenames []string
+	for _, argument := range arguments {
+		fileInfo, err := os.Stat(argument)
+		if err != nil {
+			return nil, fmt.Errorf("os.Stat: %v", err)
+		}
+		if !fileInfo.IsDir() {
+			filenames = append(filenames, argument)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(argument, "*.cmd"))
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Glob: %v", err)
+		}
+		sort.Strings(matches)
+		filenames = append(filenames, matches...)
+	}
+	return filenames, nil
+}
+
+// Run the test script in a session of its own and return whether it passed and its transcript
+//
+// A test fails, if a check fails, a command fails without a check of it by the next command, the last command fails
+// or it quits with a status other than 0.
+func (c *Commander) runTest(ctx context.Context, filename string) (bool, string) {
+
+	transcript := &bytes.Buffer{}
+	session := c.newTestSession(transcript)
+
+	ctx, cancel := context.WithCancel(withExecution(ctx, session.foreground))
+	defer cancel()
+
+	// 'quit' ends the test instead of the process
+	quit, quitStatus := false, 0
+	session.exit = func(status int) {
+		quit, quitStatus = true, status
+		cancel()
+	}
+
+	err := session.runScriptFile(ctx, filename, []string{filename}, false, false)
+	if err != nil && err != errReported {
+		_, _ = fmt.Fprintf(transcript, "error: %v\n", err)
+	}
+	if quit {
+		err = nil
+	}
+	passed := session.getFailedChecks() == 0 && session.getUncheckedFailures() == 0
+	return err == nil && quitStatus == 0 && passed, transcript.String()
+}
+
+func (c *Commander) testCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'test dir|file...'")
+	}
+
+	filenames, err := testScripts(arguments)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no test scripts '*.cmd' found")
+	}
+
+	// Run the tests one after the other and print the transcripts of the failed ones
+	failed := 0
+	for _, filename := range filenames {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		start := time.Now()
+		ok, transcript := c.runTest(ctx, filename)
+		duration := time.Since(start).Round(time.Millisecond)
+		c.logs.Info("Test finished", "file", filename, "duration", duration, "status", ok)
+
+		if ok {
+			_, _ = fmt.Fprintf(Output(ctx), "PASS  %s (%v)\n", filename, duration)
+			continue
+		}
+		failed++
+		_, _ = fmt.Fprintf(Output(ctx), "FAIL  %s (%v)\n", filename, duration)
+		for _, line := range strings.Split(strings.TrimRight(transcript, "\n"), "\n") {
+			_, _ = fmt.Fprintf(Output(ctx), "\t%s\n", line)
+		}
+	}
+
+	_, _ = fmt.Fprintf(Output(ctx), "%d passed, %d failed\n", len(filenames)-failed, failed)
+	if failed > 0 {
+		return nil, fmt.Errorf("%d of %d tests failed", failed, len(filenames))
+	}
+	return nil, nil
+}
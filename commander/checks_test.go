@@ -0,0 +1,143 @@
+package commander
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateAssertion(t *testing.T) {
+
+	tests := []struct {
+		arguments []string
+		want      bool
+	}{
+		{[]string{"x"}, true},
+		{[]string{""}, false},
+		{[]string{"0"}, false},
+		{[]string{"false"}, false},
+		{[]string{"!", "0"}, true},
+		{[]string{"a", "==", "a"}, true},
+		{[]string{"a", "==", "b"}, false},
+		{[]string{"a", "!=", "b"}, true},
+		{[]string{"!", "a", "!=", "b"}, false},
+		{[]string{"QmHash", "=~", "^Qm"}, true},
+		{[]string{"QmHash", "!~", "^Qm"}, false},
+		{[]string{"9", "<", "10"}, true},
+		{[]string{"10", "<=", "9"}, false},
+		{[]string{"1.5", ">", "1"}, true},
+		{[]string{"2", ">=", "2"}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := evaluateAssertion(tt.arguments)
+		if err != nil {
+			t.Errorf("evaluateAssertion(%q): unexpected error: %v", tt.arguments, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("evaluateAssertion(%q) = %v, want %v", tt.arguments, got, tt.want)
+		}
+	}
+
+	for _, arguments := range [][]string{{}, {"a", "=="}, {"a", "<>", "b"}, {"a", "<", "1"}, {"a", "=~", "("}} {
+		if _, err := evaluateAssertion(arguments); err == nil {
+			t.Errorf("evaluateAssertion(%q): expected error", arguments)
+		}
+	}
+}
+
+func TestChecks(t *testing.T) {
+
+	tests := []struct {
+		line   string
+		wantOK bool
+	}{
+		{"echo hello world; expect ^hello", true},
+		{"echo hello world; expect ^world", false},
+		{"echo a; echo b; expect ^b$", true},
+		{"echo a; echo b; expect ^a$", false},
+		{"echo hello; expect hello; expect ^h", true},
+		{"echo hello; expect hello; assert $_ == hello", true},
+		{"echo hello; assert $_ == bye", false},
+		{"sleep x; assert $? == 1", true},
+		{"set -e; echo x; expect y", false},
+	}
+
+	for _, tt := range tests {
+		c, out := newTestCommander("")
+		if ok := c.executeCommand(tt.line); ok != tt.wantOK {
+			t.Errorf("executeCommand(%q) = %v, want %v, output %q", tt.line, ok, tt.wantOK, out.String())
+		}
+		if failed := c.getFailedChecks(); (failed == 0) != tt.wantOK {
+			t.Errorf("executeCommand(%q): %d failed checks", tt.line, failed)
+		}
+	}
+}
+
+func TestTestCommand(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-test")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	scripts := map[string]string{
+		"1-pass.cmd":    "echo hello\nexpect hello\ngreet\nexpect ^hi$\n",
+		"2-fail.cmd":    "echo hello\nexpect bye\necho after\n",
+		"3-quit.cmd":    "quit 0\necho never\nexpect never\n",
+		"4-status.cmd":  "echo before\nsleep x\n",
+		"ignored.txt":   "expect nothing\n",
+		"5-defines.cmd": "func greet\n\techo redefined\nend\n",
+		"6-middle.cmd":  "echo before\nsleep x\necho after\n",
+		"7-checked.cmd": "sleep x\nassert $? == 1\nsleep x || echo other\nif sleep x\n\techo no\nend\necho after\n",
+	}
+	for name, content := range scripts {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("ioutil.WriteFile(): %v", err)
+		}
+	}
+
+	c, out := newTestCommander("")
+	c.Register(Command{Name: "greet", Handler: func(ctx context.Context, arguments []string) (interface{}, error) {
+		return "hi", nil
+	}})
+	if c.executeCommand("test " + dir) {
+		t.Errorf("test %s succeeded, want failure", dir)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"PASS  " + filepath.Join(dir, "1-pass.cmd"),
+		"FAIL  " + filepath.Join(dir, "2-fail.cmd"),
+		"\terror: expected \"bye\" in output \"hello\\n\"",
+		"PASS  " + filepath.Join(dir, "3-quit.cmd"),
+		"FAIL  " + filepath.Join(dir, "4-status.cmd"),
+		"FAIL  " + filepath.Join(dir, "5-defines.cmd"),
+		"FAIL  " + filepath.Join(dir, "6-middle.cmd"),
+		"PASS  " + filepath.Join(dir, "7-checked.cmd"),
+		"3 passed, 4 failed\n",
+		"error: 4 of 7 tests failed\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("test %s printed %q, missing %q", dir, got, want)
+		}
+	}
+	if strings.Contains(got, "ignored.txt") {
+		t.Errorf("test %s printed %q", dir, got)
+	}
+
+	// The tests run in strict mode like the session
+	c.strictMode = true
+	out.Reset()
+	if c.executeCommand("test " + filepath.Join(dir, "7-checked.cmd")) {
+		t.Errorf("test 7-checked.cmd succeeded in strict mode, want failure, output %q", out.String())
+	}
+}
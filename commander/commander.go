@@ -0,0 +1,994 @@
+// Package commander is the core of the interactive command line tools: the loop with history and completion, the
+// registry of commands with their help, scripting, logging and the built-in commands.
+//
+// A tool registers its own commands and runs the session, e.g.
+//
+//	func main() {
+//		commander.Run("cmdtool-template", func(c *commander.Commander) {
+//			c.Register(commander.Command{Name: "play", Handler: play})
+//		})
+//	}
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandHandler is called with the arguments following the command name and returns its result or an error on
+// failure
+//
+// The context is cancelled by an interrupt, i.e. Ctrl-C, and handlers running for long have to return then.
+// The result is rendered in the output format, unless it is nil. Results are structured data like structs, maps
+// and slices, JSON field names apply, and print themselves in the plain format, if they implement fmt.Stringer.
+type CommandHandler func(ctx context.Context, arguments []string) (interface{}, error)
+
+// CommandCompleter returns the completions for the last of the arguments typed so far
+type CommandCompleter func(arguments []string) []string
+
+// Command describes an interactive command and its help
+type Command struct {
+	Name string
+
+	// Category groups the command in the help, e.g. Commander or Scripting
+	Category string
+
+	// Synopsis is the syntax of the command line, e.g. "history [-n number] [filter]"
+	Synopsis string
+
+	// Description starts with a sentence summarizing what the command does, followed by details, if any
+	Description string
+
+	Flags    []CommandFlag
+	Examples []string
+
+	Handler   CommandHandler
+	Completer CommandCompleter
+
+	// Checks the previous command, whose output and result are kept for further checks
+	check bool
+}
+
+// errReported signals a failure, which has already been reported to the user
+var errReported = errors.New("error already reported")
+
+// errInterrupted signals a command cancelled by an interrupt
+var errInterrupted = errors.New("interrupted")
+
+// The state of the execution of command lines, i.e. at the prompt, of the batch or of a background job
+//
+// It is passed to the commands with the context and keeps executions apart, which run at the same time.
+type execution struct {
+
+	// The commander executing the command lines
+	commander *Commander
+
+	// Output of the commands, results and errors
+	out io.Writer
+
+	// Result and status of the last command for '$_' and '$?', the status is guarded by the state mutex
+	result string
+	status bool
+
+	// Output of the last command, except of checks, for 'expect'
+	lastOutput string
+
+	// Name and arguments of the running script or function for '$0', '$1'... and '$#'
+	arguments []string
+
+	// Scripts and functions in execution, the innermost last
+	stack []*scriptFrame
+
+	// Aliases in expansion, the innermost last, which are not expanded again
+	expanding []string
+
+	// Set while evaluating the condition of 'if', whose failures never stop a script
+	condition bool
+
+	// Set while running the startup file shared by all tools, which skips commands and prompt fields of other tools
+	shared bool
+
+	// Set after a failure not checked by '&&', '||' or 'if', which the next command has to check in tests
+	unchecked bool
+}
+
+// Return the status of the last command, which is read by other goroutines, e.g. for the exit on interrupts
+func (e *execution) succeeded() bool {
+
+	e.commander.stateMutex.Lock()
+	defer e.commander.stateMutex.Unlock()
+
+	return e.status
+}
+
+// Return a new execution of the commander writing to out
+func newExecution(c *Commander, out io.Writer) *execution {
+	return &execution{commander: c, out: out, status: true}
+}
+
+type executionKey struct{}
+
+// Return the context carrying the execution
+func withExecution(ctx context.Context, e *execution) context.Context {
+	return context.WithValue(ctx, executionKey{}, e)
+}
+
+// Return the execution of the context, which commands are always called with
+func executionOf(ctx context.Context) *execution {
+	e, ok := ctx.Value(executionKey{}).(*execution)
+	if !ok {
+		panic("context without execution")
+	}
+	return e
+}
+
+// Return the commander executing the command
+func commanderOf(ctx context.Context) *Commander {
+	return executionOf(ctx).commander
+}
+
+// Logs returns the logger of the session executing the command
+func Logs(ctx context.Context) *Logger {
+	return commanderOf(ctx).logs
+}
+
+// Output returns the writer for the output of the command, e.g. the buffer of a background job
+func Output(ctx context.Context) io.Writer {
+	return executionOf(ctx).out
+}
+
+// An unknown command with the similar commands, if any
+type unknownCommandError struct {
+	name        string
+	suggestions []string
+}
+
+func (e *unknownCommandError) Error() string {
+
+	if len(e.suggestions) == 0 {
+		return fmt.Sprintf("unknown command %q, 'help' lists the commands available", e.name)
+	}
+	quoted := make([]string, len(e.suggestions))
+	for i, suggestion := range e.suggestions {
+		quoted[i] = strconv.Quote(suggestion)
+	}
+	if len(quoted) == 1 {
+		return fmt.Sprintf("unknown command %q, did you mean %s?", e.name, quoted[0])
+	}
+	return fmt.Sprintf("unknown command %q, did you mean %s or %s?", e.name,
+		strings.Join(quoted[:len(quoted)-1], ", "), quoted[len(quoted)-1])
+}
+
+// Commander is a session executing command lines with its own commands, variables, aliases, history, jobs and logging
+//
+// Several commanders can run in one process, e.g. in tests, each with its own input and output.
+type Commander struct {
+
+	// Name of the session, e.g. for the prompt and the files of history and logging
+	name string
+
+	// Input of the batch and output of the commands at the prompt or of the batch
+	in  io.Reader
+	out io.Writer
+
+	// Exits the process with the status, i.e. by 'quit' or a second interrupt at the prompt
+	exit func(status int)
+
+	// Logger of the session, the files logged to by the session and by 'log on', the current one last, and their
+	// rotation
+	logs     *Logger
+	logMutex sync.Mutex
+	logStack []*rotatingFile
+	rotation logRotation
+
+	// Guards the state shared by all executions, i.e. commands, functions, variables, aliases and settings
+	stateMutex sync.Mutex
+
+	// Commands by name and their names in sorted order
+	commands    map[string]*Command
+	commandKeys []string
+
+	// Names of the commands defined as functions, which may be redefined
+	functions map[string]bool
+
+	// Variables set by 'set'
+	variables map[string]string
+
+	// Aliases by name with the command lines they expand to and the file to save them, empty for no persistence
+	aliases       map[string]string
+	aliasFilename string
+
+	// Strict mode stops scripts at the first failing command
+	strictMode bool
+
+	// Number of failed checks, i.e. 'expect' and 'assert', and of failures not checked by the next command
+	failedChecks      int
+	uncheckedFailures int
+
+	// Recording of the command lines at the prompt, if any
+	recording *recorder
+
+	// The format the results of commands are rendered in, set by '-o' or 'output'
+	outputFormat string
+
+	// Batch mode runs without prompt and echo of script lines
+	batchMode bool
+
+	// Templates of the prompt and of the lines of scripts echoed, and the fields to use in them
+	promptFormat       string
+	scriptPromptFormat string
+	promptFields       map[string]*PromptField
+
+	// Command lines of this and former sessions, the latest last, the file to save them, empty for no persistence,
+	// and their maximum number
+	history         []string
+	historyFilename string
+	historySize     int
+
+	// Jobs by id, until they are waited for
+	jobs      map[int]*job
+	lastJobID int
+	jobsMutex sync.Mutex
+
+	// The execution at the prompt or of the batch
+	foreground *execution
+
+	// Cancels the command line running at the prompt, if any, and whether the last input at the prompt was an interrupt
+	cancelMutex       sync.Mutex
+	cancelRunning     context.CancelFunc
+	promptInterrupted bool
+}
+
+// New returns a session of the name with the built-in commands, reading a batch from in and writing to out
+//
+// Logging is off, until switched on by 'log on', and only the sessions of Run persist history and aliases.
+func New(name string, in io.Reader, out io.Writer) *Commander {
+
+	c := &Commander{
+		name:               name,
+		in:                 in,
+		out:                out,
+		exit:               os.Exit,
+		logs:               &Logger{out: ioutil.Discard, level: levelInfo, errOut: os.Stderr},
+		rotation:           logRotation{retain: 5, compress: true},
+		commands:           make(map[string]*Command),
+		functions:          make(map[string]bool),
+		variables:          make(map[string]string),
+		aliases:            make(map[string]string),
+		outputFormat:       "plain",
+		promptFormat:       defaultPromptFormat,
+		scriptPromptFormat: defaultScriptPromptFormat,
+		promptFields:       make(map[string]*PromptField),
+		historySize:        1000,
+		jobs:               make(map[int]*job),
+	}
+	c.foreground = newExecution(c, out)
+	c.commandsInit()
+	c.promptFieldsInit()
+
+	return c
+}
+
+// Register adds a new command or replaces an existing one with the same name
+func (c *Commander) Register(command Command) *Command {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	name := command.Name
+	if _, ok := c.commands[name]; !ok {
+
+		// To store the keys in sorted order
+		i := sort.SearchStrings(c.commandKeys, name)
+		c.commandKeys = append(c.commandKeys, "")
+		copy(c.commandKeys[i+1:], c.commandKeys[i:])
+		c.commandKeys[i] = name
+	}
+
+	c.commands[name] = &command
+
+	return &command
+}
+
+// Return the command of the name
+func (c *Commander) lookupCommand(name string) (*Command, bool) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	command, ok := c.commands[name]
+	return command, ok
+}
+
+// Return the names of the commands in sorted order
+func (c *Commander) commandNames() []string {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return append([]string{}, c.commandKeys...)
+}
+
+func (c *Commander) commandsInit() {
+
+	// Commander
+	c.Register(Command{
+		Name:     "log",
+		Category: CategoryCommander,
+		Synopsis: "log (on <filename>)|off|(level <level>)|status",
+		Description: "Switches the logging output to another file and back, sets the level or shows the status.\n\n" +
+			"'on' starts writing the logging output to the file and 'off' stops it, returning to the former one. " +
+			"'level' sets the minimal level to debug, info, warn or error. 'status' shows the files, the current one first, " +
+			"and the level.",
+		Examples:  []string{"log on debug.log", "log level debug", "log off"},
+		Handler:   c.cmdLogging,
+		Completer: logCompleter,
+	})
+	c.Register(Command{
+		Name:     "alias",
+		Category: CategoryCommander,
+		Synopsis: "alias [name [= command line]]",
+		Description: "Defines, shows or lists aliases.\n\n" +
+			"The command line is executed instead of the alias. $1.. are the arguments, otherwise they are appended. " +
+			"Quote the command line with single quotes to keep operators and variables. " +
+			"The aliases are saved per tool in the home directory and restored by its next session.",
+		Examples:  []string{"alias ll = 'history -n 10'", "alias debug = 'log level debug && log status'", "alias"},
+		Handler:   c.aliasCommand,
+		Completer: c.aliasCompleter,
+	})
+	c.Register(Command{
+		Name:        "unalias",
+		Category:    CategoryCommander,
+		Synopsis:    "unalias name...",
+		Description: "Removes the aliases.",
+		Examples:    []string{"unalias ll"},
+		Handler:     c.unaliasCommand,
+		Completer:   c.aliasCompleter,
+	})
+	c.Register(Command{
+		Name:     "history",
+		Category: CategoryCommander,
+		Synopsis: "history [-n number] [filter]",
+		Description: "Lists the command lines of the history containing the filter.\n\n" +
+			"At the prompt '!n' executes the n-th command line again, '!-n' the n-th last one, '!!' the last one " +
+			"and '!prefix' the last one starting with the prefix.",
+		Flags:    []CommandFlag{{"-n number", "lists the last number command lines only"}},
+		Examples: []string{"history -n 10 add", "!!"},
+		Handler:  c.historyCommand,
+	})
+	c.Register(Command{
+		Name:     "record",
+		Category: CategoryCommander,
+		Synopsis: "record [(on [-expect] <file>)|off]",
+		Description: "Records the successful command lines at the prompt to the file as script.\n\n" +
+			"Each command line is preceded by its timestamp as comment. 'off' stops the recording, " +
+			"without arguments the file recorded to is shown.",
+		Flags:     []CommandFlag{{"-expect", "records the output of the command lines as expect lines"}},
+		Examples:  []string{"record on -expect session.cmd", "record off"},
+		Handler:   c.recordCommand,
+		Completer: recordCompleter,
+	})
+	c.Register(Command{
+		Name:        "output",
+		Category:    CategoryCommander,
+		Synopsis:    "output [plain|json|yaml|table]",
+		Description: "Sets the format of the results of commands or shows the current one.",
+		Examples:    []string{"output json"},
+		Handler:     c.outputCommand,
+		Completer:   outputCompleter,
+	})
+	c.Register(Command{
+		Name:        "jobs",
+		Category:    CategoryCommander,
+		Synopsis:    "jobs",
+		Description: "Lists the background jobs, started by a command line ending with '&'.",
+		Examples:    []string{"sleep 10 &", "jobs"},
+		Handler:     c.jobsCommand,
+	})
+	c.Register(Command{
+		Name:        "wait",
+		Category:    CategoryCommander,
+		Synopsis:    "wait [id...]",
+		Description: "Waits for the jobs, by default all, and prints their output.",
+		Examples:    []string{"wait 1"},
+		Handler:     c.waitCommand,
+		Completer:   c.jobCompleter,
+	})
+	c.Register(Command{
+		Name:     "fg",
+		Category: CategoryCommander,
+		Synopsis: "fg [id]",
+		Description: "Prints the output of the job, by default the last one, and follows it until it ends.\n\n" +
+			"An interrupt, i.e. Ctrl-C, cancels the job.",
+		Examples:  []string{"fg 1"},
+		Handler:   c.fgCommand,
+		Completer: c.jobCompleter,
+	})
+	c.Register(Command{
+		Name:        "kill",
+		Category:    CategoryCommander,
+		Synopsis:    "kill id...",
+		Description: "Cancels the jobs.",
+		Examples:    []string{"kill 1 2"},
+		Handler:     c.killCommand,
+		Completer:   c.jobCompleter,
+	})
+	c.Register(Command{
+		Name:        "quit",
+		Category:    CategoryCommander,
+		Synopsis:    "quit [status]",
+		Description: "Closes the session and exits with the status, by default the one of the last command.",
+		Examples:    []string{"quit", "quit 1"},
+		Handler:     c.quitCmdTool,
+	})
+	c.Register(Command{
+		Name:     "prompt",
+		Category: CategoryCommander,
+		Synopsis: "prompt [-script] [template]",
+		Description: "Sets the template of the prompt or shows the templates and the fields available.\n\n" +
+			"Fields in braces are replaced by their current value, e.g. '{time}', '{name}' of the session " +
+			"or '{status}' of the last command. Tools add their own fields.",
+		Flags:     []CommandFlag{{"-script", "sets the template of the lines of scripts echoed, with the field '{script}'"}},
+		Examples:  []string{"prompt '< {time} {name} {status}> '", "prompt -script '{script}> '", "prompt"},
+		Handler:   c.promptCommand,
+		Completer: c.promptCompleter,
+	})
+	c.Register(Command{
+		Name:        "help",
+		Category:    CategoryCommander,
+		Synopsis:    "help [command]",
+		Description: "Shows the help of the command or lists all commands by category.",
+		Examples:    []string{"help", "help history"},
+		Handler:     c.helpCommand,
+		Completer:   c.helpCompleter,
+	})
+
+	// Scripting
+	c.Register(Command{
+		Name:     "execute",
+		Category: CategoryScripting,
+		Synopsis: "execute file [arguments]",
+		Description: "Executes the commands in the file line by line.\n\n" +
+			"'#' starts a comment, $0 is the file, $1.. are the arguments and $# is their number. " +
+			"Blocks of 'if', 'for', 'repeat' and 'func' end with 'end'.",
+		Examples:  []string{"execute regression.cmd QmHash"},
+		Handler:   c.executeScript,
+		Completer: FileCompleter,
+	})
+	c.Register(Command{
+		Name:     "source",
+		Category: CategoryScripting,
+		Synopsis: "source file [arguments]",
+		Description: "Executes the file like execute, but relative to the calling script.\n\n" +
+			"Without arguments the script keeps the arguments of the caller.",
+		Examples:  []string{"source lib/functions.cmd"},
+		Handler:   c.sourceScript,
+		Completer: FileCompleter,
+	})
+	c.Register(Command{
+		Name:        "sleep",
+		Category:    CategoryScripting,
+		Synopsis:    "sleep seconds",
+		Description: "Sleeps for the seconds.",
+		Examples:    []string{"sleep 5"},
+		Handler:     sleepScript,
+	})
+	c.Register(Command{
+		Name:        "echo",
+		Category:    CategoryScripting,
+		Synopsis:    "echo text_w/o_linebreak",
+		Description: "Prints the rest of the line.",
+		Examples:    []string{"echo hello $name"},
+		Handler:     echoScript,
+	})
+	c.Register(Command{
+		Name:     "set",
+		Category: CategoryScripting,
+		Synopsis: "set [name [value]]|-e|+e",
+		Description: "Sets the variable to the value or lists all variables.\n\n" +
+			"$name or ${name} substitutes the value, $_ is the result and $? the status of the last command.",
+		Flags: []CommandFlag{
+			{"-e", "stops scripts at the first failing command"},
+			{"+e", "continues scripts after failing commands"},
+		},
+		Examples:  []string{"set name world", "set -e"},
+		Handler:   c.setVariable,
+		Completer: c.setCompleter,
+	})
+	c.Register(Command{
+		Name:        "unset",
+		Category:    CategoryScripting,
+		Synopsis:    "unset name...",
+		Description: "Removes the variables.",
+		Examples:    []string{"unset name"},
+		Handler:     c.unsetVariable,
+		Completer:   c.variableCompleter,
+	})
+	c.Register(Command{
+		Name:     "expect",
+		Category: CategoryScripting,
+		Synopsis: "expect regex",
+		Description: "Checks that the output of the previous command matches the regular expression.\n\n" +
+			"'^' and '$' match at line breaks. Checks keep the output and the result of the previous command.",
+		Examples: []string{"echo hello; expect ^hello$"},
+		Handler:  c.expectCommand,
+		check:    true,
+	})
+	c.Register(Command{
+		Name:     "assert",
+		Category: CategoryScripting,
+		Synopsis: "assert [!] value [(==|!=|=~|!~|<|<=|>|>=) value]",
+		Description: "Checks the expression.\n\n" +
+			"A single value holds, unless it is empty, 0 or false. '=~' and '!~' match regular expressions, " +
+			"'<', '<=', '>' and '>=' compare numbers and '!' negates the expression.",
+		Examples: []string{"assert $_ == hello", "assert $? == 1"},
+		Handler:  c.assertCommand,
+		check:    true,
+	})
+	c.Register(Command{
+		Name:     "test",
+		Category: CategoryScripting,
+		Synopsis: "test dir|file...",
+		Description: "Runs the '*.cmd' scripts as tests and reports pass or fail.\n\n" +
+			"Each test runs in a session of its own and passes, if all its checks hold, every failing command is " +
+			"checked by the next one and its last command succeeds.",
+		Examples:  []string{"test tests"},
+		Handler:   c.testCommand,
+		Completer: FileCompleter,
+	})
+
+	// Developer
+	c.Register(Command{
+		Name:        "docs",
+		Category:    CategoryDeveloper,
+		Synopsis:    "docs [file]",
+		Description: "Writes the reference of all commands as Markdown to the file or prints it.",
+		Examples:    []string{"docs COMMANDS.md"},
+		Handler:     c.docsCommand,
+		Completer:   FileCompleter,
+	})
+}
+
+// Return whether scripts stop at the first failing command
+func (c *Commander) strict() bool {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return c.strictMode
+}
+
+// Return whether a failure stops the running script, i.e. in strict mode outside of conditions
+func (c *Commander) stopOnFailure(ctx context.Context) bool {
+	e := executionOf(ctx)
+	return len(e.stack) > 0 && !e.condition && c.strict()
+}
+
+// Operators chaining the commands of a command line
+const (
+	chainSequence = ";"
+	chainAnd      = "&&"
+	chainOr       = "||"
+
+	// Runs the command line in the background, if it ends with it
+	chainBackground = "&"
+)
+
+// A command of a command line together with the operator chaining it to its predecessor
+type chainedCommand struct {
+	operator    string
+	commandline string
+}
+
+// Split a command line at the operator tokens into its chained commands
+//
+// Every operator needs a command before it, '&&' and '||' need one after it as well.
+func splitCommandline(commandline string, tokens []token) ([]chainedCommand, error) {
+
+	var chain []chainedCommand
+
+	operator := chainSequence
+	start := 0
+	empty := true
+	for _, t := range tokens {
+		if t.kind != tokenOperator {
+			empty = false
+			continue
+		}
+		if empty {
+			return nil, fmt.Errorf("syntax error near %q", t.value)
+		}
+		chain = append(chain, chainedCommand{operator, commandline[start:t.pos]})
+		operator = t.value
+		start = t.pos + len(t.value)
+		empty = true
+	}
+	if empty && (operator == chainAnd || operator == chainOr) {
+		return nil, fmt.Errorf("syntax error near %q", operator)
+	}
+	return append(chain, chainedCommand{operator, commandline[start:]}), nil
+}
+
+// Cancel the command line running at the prompt on interrupts instead of exiting
+//
+// Interrupts at the prompt are signals only, if the terminal is not supported by liner.
+func (c *Commander) handleInterrupts() {
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			c.interrupt()
+		}
+	}()
+}
+
+// Cancel the command line running at the prompt, if any, otherwise exit at the second interrupt in a row
+func (c *Commander) interrupt() {
+
+	c.cancelMutex.Lock()
+	cancel := c.cancelRunning
+	c.cancelMutex.Unlock()
+
+	if cancel != nil {
+		c.logs.Info("Interrupt running command")
+		cancel()
+	} else if c.interruptPrompt() {
+		_, _ = fmt.Fprintln(c.out)
+		c.exit(c.exitStatus())
+	}
+}
+
+// Register an interrupt at the prompt and return true for the second one in a row, i.e. to exit
+func (c *Commander) interruptPrompt() bool {
+
+	c.cancelMutex.Lock()
+	defer c.cancelMutex.Unlock()
+
+	if c.promptInterrupted {
+		return true
+	}
+	c.promptInterrupted = true
+	_, _ = fmt.Fprintf(c.out, "\n(press Ctrl-C again to exit)\n")
+	return false
+}
+
+// Set the function cancelling the command line running at the prompt
+func (c *Commander) setCancelRunning(cancel context.CancelFunc) {
+	c.cancelMutex.Lock()
+	defer c.cancelMutex.Unlock()
+	c.cancelRunning = cancel
+	c.promptInterrupted = false
+}
+
+// Return the exit status of the session, i.e. 1, if the last command at the prompt or of the batch failed
+func (c *Commander) exitStatus() int {
+	if !c.foreground.succeeded() {
+		return 1
+	}
+	return 0
+}
+
+// Execute a command line specified by the argument string, i.e. one or more commands chained by ';', '&&' or '||'
+//
+// An interrupt cancels the command line.
+func (c *Commander) executeCommand(commandline string) bool {
+
+	ctx, cancel := context.WithCancel(withExecution(context.Background(), c.foreground))
+	defer cancel()
+	c.setCancelRunning(cancel)
+	defer c.setCancelRunning(nil)
+
+	return c.executeChain(ctx, commandline, func(err error) {
+		_, _ = fmt.Fprintf(c.out, "error: %v\n", err)
+	})
+}
+
+// Execute the chained commands of the command line and return the status of the last executed command
+//
+// Errors are passed to report, unless they have been reported already. A cancelled context stops the execution.
+func (c *Commander) executeChain(ctx context.Context, commandline string, report func(err error)) bool {
+
+	// Find the operators without substituting variables
+	tokens, err := tokenize(commandline, nil)
+	if err != nil {
+		report(err)
+		return false
+	}
+	chain, err := splitCommandline(commandline, tokens)
+	if err != nil {
+		report(err)
+		return false
+	}
+
+	// Start the command line as background job, if it ends with '&'
+	for i, t := range tokens {
+		if t.kind == tokenOperator && t.value == chainBackground {
+			if i < len(tokens)-1 {
+				report(fmt.Errorf("'%s' is allowed at the end of a command line only", chainBackground))
+				return false
+			}
+			j := c.startJob(ctx, commandline[:t.pos])
+			executionOf(ctx).lastOutput = ""
+			_, _ = fmt.Fprintf(Output(ctx), "[%d] %s\n", j.id, j.commandline)
+			SetResult(ctx, strconv.Itoa(j.id))
+			setStatus(ctx, true)
+			return true
+		}
+	}
+
+	ok := false
+	for i, chained := range chain {
+
+		// Stop after an interrupt
+		if ctx.Err() != nil {
+			return false
+		}
+
+		// Skip according to the status of the predecessor
+		if i > 0 && ((chained.operator == chainAnd && !ok) || (chained.operator == chainOr && ok)) {
+			continue
+		}
+
+		// Expand an alias
+		if expanded, aliasOK := c.executeAlias(ctx, chained.commandline, report); expanded {
+			ok = aliasOK
+			setStatus(ctx, ok)
+		} else {
+
+			// Substitute variables just before the execution to see the results of the predecessors
+			tokens, err := tokenize(chained.commandline, variableLookup(ctx))
+			if err == nil && len(tokens) == 0 {
+
+				// Empty command
+				continue
+			}
+			if err == nil {
+				commandFields := make([]string, len(tokens))
+				for j, t := range tokens {
+					commandFields[j] = t.value
+				}
+				err = c.runCommand(ctx, commandFields)
+			}
+
+			ok = err == nil
+			setStatus(ctx, ok)
+			if err != nil && err != errReported {
+				report(err)
+			}
+		}
+
+		// Leave a failure to the next command to check, unless it is checked by a following '&&' or '||'
+		e := executionOf(ctx)
+		e.unchecked = !ok && !e.condition && (i+1 == len(chain) || chain[i+1].operator == chainSequence)
+
+		// Stop at a failure in strict mode, unless it is checked by a following '&&' or '||'
+		if !ok && i+1 < len(chain) && chain[i+1].operator == chainSequence && c.stopOnFailure(ctx) {
+			return false
+		}
+	}
+	return ok
+}
+
+// Look up the first word and call the handler with the rest as arguments
+func (c *Commander) runCommand(ctx context.Context, commandFields []string) error {
+
+	e := executionOf(ctx)
+	command, found := c.lookupCommand(commandFields[0])
+	if !found && e.shared {
+		c.logs.Info("Command of another tool skipped", "command", commandFields[0])
+		return nil
+	}
+
+	// A failure of the previous command not checked by this one fails a test
+	if e.unchecked && !(found && command.check) {
+		c.failUnchecked()
+	}
+	e.unchecked = false
+
+	if !found {
+		return &unknownCommandError{commandFields[0], c.suggestCommands(commandFields[0])}
+	}
+
+	// Capture the output of the command for checks, unless it is a check itself
+	out := e.out
+	captured := &cappedBuffer{capacity: maxCapturedOutput}
+	if !command.check {
+		SetResult(ctx, "")
+		e.out = io.MultiWriter(out, captured)
+	}
+
+	start := time.Now()
+	result, err := command.Handler(ctx, commandFields[1:])
+	if err != nil && err != errReported && ctx.Err() != nil {
+		err = errInterrupted
+	}
+	if err == nil && result != nil {
+		err = renderResult(Output(ctx), c.getOutputFormat(), result)
+	}
+
+	if !command.check {
+		e.out = out
+		e.lastOutput = captured.String()
+	}
+
+	// Log every invocation with its outcome
+	if err != nil && err != errReported {
+		c.logs.Warn("Command failed", "command", commandFields[0], "arguments", commandFields[1:],
+			"duration", time.Since(start), "error", err)
+	} else {
+		c.logs.Info("Command executed", "command", commandFields[0], "arguments", commandFields[1:],
+			"duration", time.Since(start), "status", err == nil)
+	}
+	return err
+}
+
+// Complete the command line, i.e. the command name or the arguments of a known command of the last chained command
+func (c *Commander) completeCommand(line string) (ret []string) {
+
+	// Complete the last of the chained commands only
+	tokens, err := tokenize(line, nil)
+	if err != nil {
+		return
+	}
+	head := ""
+	for _, t := range tokens {
+		if t.kind == tokenOperator {
+			head = line[:t.pos+len(t.value)]
+		}
+	}
+	commandline := line[len(head):]
+	head += commandline[:len(commandline)-len(strings.TrimLeft(commandline, " \t"))]
+	commandline = line[len(head):]
+
+	commandFields := strings.Fields(commandline)
+
+	// Complete the command name or alias
+	if len(commandFields) == 0 || (len(commandFields) == 1 && !strings.HasSuffix(commandline, " ")) {
+		for _, name := range append(c.commandNames(), c.aliasNames()...) {
+			if strings.HasPrefix(name, commandline) {
+				ret = append(ret, head+name)
+			}
+		}
+		return
+	}
+
+	// Complete the arguments by the completer of the command, if any
+	command, ok := c.lookupCommand(commandFields[0])
+	if !ok || command.Completer == nil {
+		return
+	}
+	arguments := commandFields[1:]
+	if strings.HasSuffix(commandline, " ") {
+		arguments = append(arguments, "")
+	}
+	last := arguments[len(arguments)-1]
+	head = line[:len(line)-len(last)]
+	for _, completion := range command.Completer(arguments) {
+		ret = append(ret, head+completion)
+	}
+	return
+}
+
+func (c *Commander) quitCmdTool(ctx context.Context, arguments []string) (interface{}, error) {
+
+	// Exit with the status of the last command by default
+	status := 0
+	if !executionOf(ctx).succeeded() {
+		status = 1
+	}
+	if len(arguments) > 0 {
+		var err error
+		status, err = strconv.Atoi(arguments[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit status %q", arguments[0])
+		}
+	}
+
+	c.exit(status)
+	return nil, nil
+}
+
+func sleepScript(ctx context.Context, arguments []string) (interface{}, error) {
+
+	numSeconds := 1
+
+	if len(arguments) > 0 {
+		var err error
+		numSeconds, err = strconv.Atoi(arguments[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of seconds %q", arguments[0])
+		}
+	}
+
+	select {
+	case <-time.After(time.Second * time.Duration(numSeconds)):
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func echoScript(ctx context.Context, arguments []string) (interface{}, error) {
+
+	text := strings.Join(arguments, " ")
+	SetResult(ctx, text)
+	return text, nil
+}
+
+// The files and the settings of logging
+type logStatus struct {
+	Files  []string `json:"files"`
+	Level  string   `json:"level"`
+	Format string   `json:"format"`
+}
+
+func (s logStatus) String() string {
+
+	var sb strings.Builder
+	if len(s.Files) == 0 {
+		sb.WriteString("logging is off\n")
+	}
+	for i, file := range s.Files {
+		current := ""
+		if i == 0 {
+			current = " (current)"
+		}
+		sb.WriteString(fmt.Sprintf("file %d: %s%s\n", len(s.Files)-i, file, current))
+	}
+	sb.WriteString(fmt.Sprintf("level: %s\nformat: %s", s.Level, s.Format))
+	return sb.String()
+}
+
+func (c *Commander) cmdLogging(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'log (on <filename>)|off|(level <level>)|status")
+	}
+
+	switch {
+	case arguments[0] == "level" && len(arguments) == 2:
+		level, err := parseLogLevel(arguments[1])
+		if err != nil {
+			return nil, err
+		}
+		c.logs.Info("Switch log level by command", "from", c.logs.getLevel(), "to", level)
+		c.logs.setLevel(level)
+
+	case arguments[0] == "on" && len(arguments) == 2:
+		c.logs.Info("Switch to logging by command", "file", arguments[1])
+		_, err := c.startLogging(arguments[1])
+		if err != nil {
+			return nil, fmt.Errorf("startLogging: %v", err)
+		}
+		c.logs.Info("Start logging by command", "file", arguments[1], "depth", c.logDepth())
+
+	case arguments[0] == "off" && len(arguments) == 1:
+		from := c.currentLogfile()
+		c.logs.Info("Stop logging by command", "file", from)
+		err := c.stopLogging()
+		if err != nil {
+			return nil, fmt.Errorf("stopLogging: %v", err)
+		}
+		c.logs.Info("Switch back from logging by command", "from", from, "depth", c.logDepth())
+
+	case arguments[0] == "status" && len(arguments) == 1:
+		status := logStatus{Files: c.logfiles(), Level: c.logs.getLevel().String(), Format: c.logs.format()}
+		SetResult(ctx, c.currentLogfile())
+		return status, nil
+
+	default:
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'log (on <filename>)|off|(level <level>)|status")
+	}
+	return nil, nil
+}
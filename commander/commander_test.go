@@ -0,0 +1,482 @@
+package commander
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Return a commander in batch mode reading the input and writing to the returned buffer
+func newTestCommander(input string) (*Commander, *bytes.Buffer) {
+
+	out := &bytes.Buffer{}
+	c := New("test", strings.NewReader(input), out)
+	c.batchMode = true
+	return c, out
+}
+
+func TestExecuteCommand(t *testing.T) {
+
+	tests := []struct {
+		line   string
+		want   string
+		wantOK bool
+	}{
+		{"echo hello world", "hello world\n", true},
+		{"set x 1; echo $x", "1\n", true},
+		{"echo a && echo b", "a\nb\n", true},
+		{"sleep x || echo fallback", "error: invalid number of seconds \"x\"\nfallback\n", true},
+		{"sleep x && echo never", "error: invalid number of seconds \"x\"\n", false},
+		{"sleep x; echo $?", "error: invalid number of seconds \"x\"\n1\n", true},
+		{"echo first; echo $_", "first\nfirst\n", true},
+		{"alias greet = 'echo hello $1'; greet you", "hello you\n", true},
+		{"alias echo = 'echo said'; echo hi", "said hi\n", true},
+		{"alias a = b; alias b = a; a", "error: alias recursion a -> b -> a\n", false},
+		{"alias a = 'echo x && a'; a", "x\nerror: alias recursion a -> a\n", false},
+		{"output json; echo x", "\"x\"\n", true},
+		{"echo a & b", "error: '&' is allowed at the end of a command line only\n", false},
+		{"&& echo b", "error: syntax error near \"&&\"\n", false},
+		{"echo a &&", "error: syntax error near \"&&\"\n", false},
+	}
+
+	for _, tt := range tests {
+		c, out := newTestCommander("")
+		ok := c.executeCommand(tt.line)
+		if got := out.String(); got != tt.want || ok != tt.wantOK {
+			t.Errorf("executeCommand(%q) = %v with output %q, want %v with %q", tt.line, ok, got, tt.wantOK, tt.want)
+		}
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"unknown", "error: unknown command \"unknown\", 'help' lists the commands available\n"},
+		{"hstory", "error: unknown command \"hstory\", did you mean \"history\"?\n"},
+		{"histroy -n 1", "error: unknown command \"histroy\", did you mean \"history\"?\n"},
+		{"ex", "error: unknown command \"ex\", did you mean \"expect\" or \"execute\"?\n"},
+		{"alias greet = 'echo hi'; gret", "error: unknown command \"gret\", did you mean \"greet\"?\n"},
+	}
+
+	for _, tt := range tests {
+		c, out := newTestCommander("")
+		if c.executeCommand(tt.line) {
+			t.Errorf("executeCommand(%q) = true, want false", tt.line)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("executeCommand(%q) printed %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	// Scripts suggest as well
+	c, out := newTestCommander("ech hello\n")
+	_ = c.runBatch("", "", nil)
+	if want := "error: unknown command \"ech\", did you mean \"echo\"?\n\tat <stdin>:1\n"; out.String() != want {
+		t.Errorf("runBatch() printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+
+	script := `
+func greet
+	echo hello $1
+end
+for n in a b
+	greet $n
+end
+set list 'c d'
+for n in $list "$list"
+	greet $n
+end
+if sleep x
+	echo no
+else
+	echo yes
+end
+echo $0 $1
+`
+	want := "hello a\nhello b\nhello c\nhello d\nhello c d\n" +
+		"error: invalid number of seconds \"x\"\n\tat <stdin>:12\n" +
+		"yes\n<stdin> arg\n"
+
+	c, out := newTestCommander(script)
+	err := c.runBatch("", "", []string{"arg"})
+	if err != nil {
+		t.Fatalf("runBatch(): unexpected error: %v", err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("runBatch() printed %q, want %q", got, want)
+	}
+}
+
+func TestRunBatchScriptFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-batch")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	scriptfile := filepath.Join(dir, "p.cmd")
+	err = ioutil.WriteFile(scriptfile, []byte("echo $# $1 $2\n"), 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile(): %v", err)
+	}
+
+	// The arguments of '-f p.cmd a b' are passed to the script
+	name, arguments := sessionArguments(true, "", []string{"a", "b"})
+	if name != "batch" || !reflect.DeepEqual(arguments, []string{"a", "b"}) {
+		t.Errorf("sessionArguments() = %q, %q, want %q, %q", name, arguments, "batch", []string{"a", "b"})
+	}
+
+	c, out := newTestCommander("")
+	err = c.runBatch("", scriptfile, arguments)
+	if err != nil {
+		t.Fatalf("runBatch(): unexpected error: %v", err)
+	}
+	if got := out.String(); got != "2 a b\n" {
+		t.Errorf("runBatch() printed %q, want %q", got, "2 a b\n")
+	}
+}
+
+func TestSessionArguments(t *testing.T) {
+
+	tests := []struct {
+		batchMode     bool
+		name          string
+		args          []string
+		wantName      string
+		wantArguments []string
+	}{
+		{false, "", []string{"alice"}, "alice", nil},
+		{false, "bob", []string{"alice"}, "bob", nil},
+		{false, "", nil, "", nil},
+		{true, "", nil, "batch", nil},
+		{true, "ci", []string{"a"}, "ci", []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		name, arguments := sessionArguments(tt.batchMode, tt.name, tt.args)
+		if name != tt.wantName || !reflect.DeepEqual(arguments, tt.wantArguments) {
+			t.Errorf("sessionArguments(%v, %q, %q) = %q, %q, want %q, %q", tt.batchMode, tt.name, tt.args,
+				name, arguments, tt.wantName, tt.wantArguments)
+		}
+	}
+}
+
+func TestRunBatchStrict(t *testing.T) {
+
+	c, out := newTestCommander("set -e\necho before\nsleep x\necho after\n")
+	err := c.runBatch("", "", nil)
+	if err != errReported {
+		t.Errorf("runBatch() = %v, want %v", err, errReported)
+	}
+	if got := out.String(); strings.Contains(got, "after") {
+		t.Errorf("runBatch() continued after failure in strict mode: %q", got)
+	}
+	if c.exitStatus() != 1 {
+		t.Errorf("exitStatus() = %d, want 1", c.exitStatus())
+	}
+}
+
+func TestRunBatchStrictChain(t *testing.T) {
+
+	tests := []struct {
+		script  string
+		want    string
+		wantErr error
+	}{
+		{"sleep x; echo after", "error: invalid number of seconds \"x\"\n\tat -c:1\n", errReported},
+		{"sleep x || echo fallback; echo after", "error: invalid number of seconds \"x\"\n\tat -c:1\nfallback\nafter\n", nil},
+		{"sleep x && echo never; echo after", "error: invalid number of seconds \"x\"\n\tat -c:1\nafter\n", nil},
+		{"if sleep x; echo condition\necho then\nend\necho after",
+			"error: invalid number of seconds \"x\"\n\tat -c:1\ncondition\nthen\nafter\n", nil},
+	}
+
+	for _, tt := range tests {
+		c, out := newTestCommander("")
+		c.strictMode = true
+		err := c.runBatch(tt.script, "", nil)
+		if err != tt.wantErr {
+			t.Errorf("runBatch(%q) = %v, want %v", tt.script, err, tt.wantErr)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("runBatch(%q) printed %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+
+	c, out := newTestCommander("")
+	if !c.executeCommand("echo background &") {
+		t.Fatalf("executeCommand(%q) failed: %q", "echo background &", out.String())
+	}
+	if !c.executeCommand("wait") {
+		t.Fatalf("executeCommand(%q) failed: %q", "wait", out.String())
+	}
+
+	want := "[1] echo background\n[1] done  echo background\nbackground\n"
+	if got := out.String(); got != want {
+		t.Errorf("job printed %q, want %q", got, want)
+	}
+	if len(c.sortedJobs()) != 0 {
+		t.Errorf("jobs left after wait: %d", len(c.sortedJobs()))
+	}
+}
+
+// Jobs done are reported with their output before the prompt and removed
+func TestNotifyJobs(t *testing.T) {
+
+	c, out := newTestCommander("")
+	if !c.executeCommand("echo background &") {
+		t.Fatalf("executeCommand(%q) failed: %q", "echo background &", out.String())
+	}
+	j, err := c.lookupJob("1")
+	if err != nil {
+		t.Fatalf("lookupJob(): %v", err)
+	}
+	<-j.done
+
+	out.Reset()
+	c.notifyJobs()
+	c.notifyJobs()
+	if got, want := out.String(), "[1] done  echo background\nbackground\n"; got != want {
+		t.Errorf("notifyJobs() printed %q, want %q", got, want)
+	}
+	if len(c.sortedJobs()) != 0 {
+		t.Errorf("jobs left after notification: %d", len(c.sortedJobs()))
+	}
+}
+
+// The output of jobs is buffered up to a maximum
+func TestJobOutput(t *testing.T) {
+
+	o := &jobOutput{buffer: cappedBuffer{capacity: 8}}
+	_, _ = fmt.Fprintln(o, "first")
+	_, _ = fmt.Fprintln(o, "second")
+
+	var b bytes.Buffer
+	err := o.attach(&b)
+	if err != nil {
+		t.Fatalf("attach(): %v", err)
+	}
+	_, _ = fmt.Fprintln(o, "third")
+	if got, want := b.String(), "first\nse[5 bytes of output dropped]\nthird\n"; got != want {
+		t.Errorf("job output = %q, want %q", got, want)
+	}
+}
+
+// An interrupt cancels the running command line, two in a row at the prompt exit
+func TestInterrupt(t *testing.T) {
+
+	c, out := newTestCommander("")
+	exited := -1
+	c.exit = func(status int) {
+		exited = status
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- c.executeCommand("echo before; sleep 10; echo after")
+	}()
+
+	// Read the status meanwhile like the prompt does
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = c.exitStatus()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	for running := false; !running; time.Sleep(10 * time.Millisecond) {
+		c.cancelMutex.Lock()
+		running = c.cancelRunning != nil
+		c.cancelMutex.Unlock()
+	}
+	time.Sleep(50 * time.Millisecond)
+	c.interrupt()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Errorf("executeCommand() = true after interrupt, want false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("executeCommand() not cancelled by interrupt")
+	}
+	if got, want := out.String(), "before\nerror: interrupted\n"; got != want {
+		t.Errorf("executeCommand() printed %q, want %q", got, want)
+	}
+	if c.exitStatus() != 1 {
+		t.Errorf("exitStatus() = %d, want 1", c.exitStatus())
+	}
+
+	c.interrupt()
+	if exited != -1 {
+		t.Fatalf("exited with %d at the first interrupt at the prompt", exited)
+	}
+	c.interrupt()
+	if exited != 1 {
+		t.Errorf("exited with %d at the second interrupt at the prompt, want 1", exited)
+	}
+}
+
+// Set the home directory to a temporary one and return it and the function restoring the former one
+func setTestHome(t *testing.T) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-home")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	home := os.Getenv("HOME")
+	_ = os.Setenv("HOME", dir)
+	return dir, func() {
+		_ = os.Setenv("HOME", home)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// Each tool restores its own aliases only
+func TestAliasesPerTool(t *testing.T) {
+
+	_, restore := setTestHome(t)
+	defer restore()
+
+	c, out := newTestCommander("")
+	if err := c.aliasesInit("tool-a"); err != nil {
+		t.Fatalf("aliasesInit(): unexpected error: %v", err)
+	}
+	if !c.executeCommand("alias greet = 'echo hi'") {
+		t.Fatalf("alias failed: %q", out.String())
+	}
+
+	for _, tt := range []struct {
+		tool string
+		want bool
+	}{
+		{"tool-a", true},
+		{"tool-b", false},
+	} {
+		c, _ := newTestCommander("")
+		if err := c.aliasesInit(tt.tool); err != nil {
+			t.Fatalf("aliasesInit(%q): unexpected error: %v", tt.tool, err)
+		}
+		if _, ok := c.lookupAlias("greet"); ok != tt.want {
+			t.Errorf("alias greet restored by %s: %v, want %v", tt.tool, ok, tt.want)
+		}
+	}
+}
+
+// Sessions with the same name append to the history file, which is trimmed when loaded
+func TestHistoryFile(t *testing.T) {
+
+	dir, restore := setTestHome(t)
+	defer restore()
+	filename := filepath.Join(dir, ".cmdtool_test_history")
+	err := ioutil.WriteFile(filename, []byte("a\nb\na\nc\nd\n"), 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile(): %v", err)
+	}
+
+	first, _ := newTestCommander("")
+	first.historySize = 3
+	if err := first.historyInit(); err != nil {
+		t.Fatalf("historyInit(): unexpected error: %v", err)
+	}
+	if got, want := first.historyLines(), []string{"a", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("historyLines() = %q, want %q", got, want)
+	}
+	checkContent(t, filename, "a\nc\nd\n")
+
+	second, _ := newTestCommander("")
+	if err := second.historyInit(); err != nil {
+		t.Fatalf("historyInit(): unexpected error: %v", err)
+	}
+	for _, add := range []struct {
+		c    *Commander
+		line string
+	}{
+		{first, "echo 1"},
+		{second, "echo 2"},
+		{first, "echo 3"},
+	} {
+		if err := add.c.addHistory(add.line); err != nil {
+			t.Fatalf("addHistory(%q): unexpected error: %v", add.line, err)
+		}
+	}
+	checkContent(t, filename, "a\nc\nd\necho 1\necho 2\necho 3\n")
+}
+
+func TestQuit(t *testing.T) {
+
+	c, _ := newTestCommander("")
+	status := -1
+	c.exit = func(s int) {
+		status = s
+	}
+
+	c.executeCommand("quit 3")
+	if status != 3 {
+		t.Errorf("quit 3 exited with %d, want 3", status)
+	}
+
+	c.executeCommand("sleep x; quit")
+	if status != 1 {
+		t.Errorf("quit after failure exited with %d, want 1", status)
+	}
+}
+
+// Sessions run at the same time without sharing commands, variables, aliases or jobs
+func TestConcurrentCommanders(t *testing.T) {
+
+	for i := 0; i < 8; i++ {
+		i := i
+		t.Run(fmt.Sprintf("session%d", i), func(t *testing.T) {
+			t.Parallel()
+
+			script := fmt.Sprintf(`
+set id %d
+alias show = 'echo session $1'
+func twice
+	show $id
+	show $id
+end
+twice &
+repeat 3
+	set count $id
+end
+wait
+echo ${count}
+`, i)
+			want := fmt.Sprintf("[1] twice\n[1] done  twice\nsession %d\nsession %d\n%d\n", i, i, i)
+
+			c, out := newTestCommander(script)
+			err := c.runBatch("", "", nil)
+			if err != nil {
+				t.Fatalf("runBatch(): unexpected error: %v", err)
+			}
+			if got := out.String(); got != want {
+				t.Errorf("runBatch() printed %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package commander
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CompleteWords returns the words starting with the prefix in sorted order
+func CompleteWords(prefix string, words ...string) []string {
+
+	var ret []string
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			ret = append(ret, word)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Return the paths of the files starting with the prefix, directories end with a separator
+func completeFiles(prefix string) []string {
+
+	dir, base := filepath.Split(prefix)
+	listDir := dir
+	if len(listDir) == 0 {
+		listDir = "."
+	}
+
+	fileInfos, err := ioutil.ReadDir(listDir)
+	if err != nil {
+		return nil
+	}
+
+	var ret []string
+	for _, fileInfo := range fileInfos {
+		if !strings.HasPrefix(fileInfo.Name(), base) {
+			continue
+		}
+
+		// Hide dot files unless asked for
+		if strings.HasPrefix(fileInfo.Name(), ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		path := dir + fileInfo.Name()
+		if fileInfo.IsDir() {
+			path += string(os.PathSeparator)
+		}
+		ret = append(ret, path)
+	}
+	return ret
+}
+
+// FileCompleter completes the file names of commands with files as arguments
+func FileCompleter(arguments []string) []string {
+	return completeFiles(arguments[len(arguments)-1])
+}
+
+// Completer of the log command
+func logCompleter(arguments []string) []string {
+
+	switch {
+	case len(arguments) == 1:
+		return CompleteWords(arguments[0], "on", "off", "level", "status")
+	case len(arguments) == 2 && arguments[0] == "on":
+		return completeFiles(arguments[1])
+	case len(arguments) == 2 && arguments[0] == "level":
+		return CompleteWords(arguments[1], levelNames...)
+	}
+	return nil
+}
+
+// Completer of commands with variable names as arguments
+func (c *Commander) variableCompleter(arguments []string) []string {
+
+	c.stateMutex.Lock()
+	var names []string
+	for name := range c.variables {
+		names = append(names, name)
+	}
+	c.stateMutex.Unlock()
+
+	return CompleteWords(arguments[len(arguments)-1], names...)
+}
+
+// Completer of the set command
+func (c *Commander) setCompleter(arguments []string) []string {
+
+	if len(arguments) == 1 {
+		return append(CompleteWords(arguments[0], "-e", "+e"), c.variableCompleter(arguments)...)
+	}
+	return nil
+}
+
+// Completer of the output command
+func outputCompleter(arguments []string) []string {
+
+	if len(arguments) == 1 {
+		return CompleteWords(arguments[0], outputFormats...)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package commander
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix of the environment variables setting flag defaults, e.g. CMDTOOL_LOGLEVEL for -loglevel
+const envPrefix = "CMDTOOL_"
+
+// Flags, which are given per invocation only
+var unconfigurableFlags = map[string]bool{"c": true, "f": true, "test": true, "name": true}
+
+// Deprecated flags with the flags they are aliases of, which set the same value
+var flagAliases = map[string]string{"debugfile": "logfile"}
+
+// Return the name of the environment variable of the flag
+func flagEnvName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// Read a configuration file of the flags with lines of 'flag = value', '#' starts a comment
+//
+// The file shared by all tools may contain flags of other tools, which are skipped.
+func readConfig(flags *flag.FlagSet, filename string, shared bool) (map[string]string, error) {
+
+	config := make(map[string]string)
+
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: syntax error, expected 'flag = value'", filename, lineno)
+		}
+		key := strings.TrimSpace(parts[0])
+		if flags.Lookup(key) == nil && shared {
+			continue
+		}
+		if flags.Lookup(key) == nil || unconfigurableFlags[key] {
+			return nil, fmt.Errorf("%s:%d: unknown flag %q", filename, lineno, key)
+		}
+		config[key] = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+	}
+	return config, scanner.Err()
+}
+
+// Return the configuration file shared by all tools, empty without home directory, and the one of the session
+func configFilenames(name string) (string, string) {
+
+	shared := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		shared = filepath.Join(home, ".cmdtool.conf")
+	}
+	return shared, ".cmdtool.conf." + name
+}
+
+// Set the flags not given on the command line from the environment and the configuration files
+//
+// The environment takes precedence over the file of the session, which takes precedence over the shared one.
+func applyConfig(flags *flag.FlagSet, sharedFilename, filename string) error {
+
+	// Flags given on the command line, directly or by an alias
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+		if name, ok := flagAliases[f.Name]; ok {
+			given[name] = true
+		}
+	})
+
+	// Values of the configuration files, the ones of aliases by the flags they are aliases of
+	values := make(map[string]string)
+	for i, file := range []string{sharedFilename, filename} {
+		if len(file) == 0 {
+			continue
+		}
+		config, err := readConfig(flags, file, i == 0)
+		if err != nil {
+			return err
+		}
+		for key, value := range config {
+			if name, ok := flagAliases[key]; ok {
+				key = name
+			}
+			values[key] = value
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		_, deprecated := flagAliases[f.Name]
+		if given[f.Name] || deprecated || unconfigurableFlags[f.Name] || err != nil {
+			return
+		}
+		value, ok := os.LookupEnv(flagEnvName(f.Name))
+		for alias, name := range flagAliases {
+			if name == f.Name && !ok {
+				value, ok = os.LookupEnv(flagEnvName(alias))
+			}
+		}
+		if !ok {
+			value, ok = values[f.Name]
+		}
+		if ok {
+			if setErr := flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q of flag -%s: %v", value, f.Name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// Run the startup files '~/.cmdtoolrc' and './.cmdtoolrc.<name>' quietly, if existing
+//
+// The file shared by all tools may contain commands and prompt fields of other tools, which are skipped.
+func (c *Commander) runStartupFiles() {
+
+	var filenames []string
+	if home, err := os.UserHomeDir(); err == nil {
+		filenames = append(filenames, filepath.Join(home, ".cmdtoolrc"))
+	}
+	filenames = append(filenames, ".cmdtoolrc."+c.name)
+
+	ctx := withExecution(context.Background(), c.foreground)
+	for i, filename := range filenames {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
+		}
+		c.logs.Info("Run startup file", "file", filename)
+		c.foreground.shared = i < len(filenames)-1
+		err := c.runScriptFile(ctx, filename, []string{filename}, false, true)
+		c.foreground.shared = false
+		if err != nil && err != errReported {
+			_, _ = fmt.Fprintf(c.out, "error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package commander
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Return a temporary directory with the files of the contents by name and the function removing it
+func configTestDir(t *testing.T, files map[string]string) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-config")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666)
+		if err != nil {
+			t.Fatalf("ioutil.WriteFile(): %v", err)
+		}
+	}
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// The shared file holds the flags of both tools, each reads its own
+func TestReadConfigShared(t *testing.T) {
+
+	dir, remove := configTestDir(t, map[string]string{
+		".cmdtool.conf": "# both tools\nloglevel = debug\napi = localhost:5002\n",
+	})
+	defer remove()
+	filename := filepath.Join(dir, ".cmdtool.conf")
+
+	template := flag.NewFlagSet("cmdtool-template", flag.ContinueOnError)
+	template.String("loglevel", "info", "")
+	ipfs := flag.NewFlagSet("cmdtool-ipfs-api", flag.ContinueOnError)
+	ipfs.String("loglevel", "info", "")
+	ipfs.String("api", "localhost:5001", "")
+
+	tests := []struct {
+		flags *flag.FlagSet
+		want  map[string]string
+	}{
+		{template, map[string]string{"loglevel": "debug"}},
+		{ipfs, map[string]string{"loglevel": "debug", "api": "localhost:5002"}},
+	}
+
+	for _, tt := range tests {
+		config, err := readConfig(tt.flags, filename, true)
+		if err != nil {
+			t.Errorf("readConfig() of %s: unexpected error: %v", tt.flags.Name(), err)
+		} else if !reflect.DeepEqual(config, tt.want) {
+			t.Errorf("readConfig() of %s = %v, want %v", tt.flags.Name(), config, tt.want)
+		}
+	}
+
+	// The file of the session holds the flags of its tool only
+	if _, err := readConfig(template, filename, false); err == nil {
+		t.Errorf("readConfig() of %s with flag of another tool: expected error", template.Name())
+	}
+}
+
+// A deprecated alias in a configuration file does not override its flag given on the command line
+func TestApplyConfigAlias(t *testing.T) {
+
+	dir, remove := configTestDir(t, map[string]string{
+		".cmdtool.conf":      "debugfile = shared.log\n",
+		".cmdtool.conf.test": "loglevel = warn\n",
+	})
+	defer remove()
+
+	tests := []struct {
+		arguments []string
+		want      string
+	}{
+		{nil, "shared.log"},
+		{[]string{"-logfile", "given.log"}, "given.log"},
+		{[]string{"-debugfile", "given.log"}, "given.log"},
+	}
+
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		logfile := flags.String("logfile", "", "")
+		flags.StringVar(logfile, "debugfile", "", "")
+		loglevel := flags.String("loglevel", "info", "")
+		if err := flags.Parse(tt.arguments); err != nil {
+			t.Fatalf("Parse(%q): %v", tt.arguments, err)
+		}
+
+		err := applyConfig(flags, filepath.Join(dir, ".cmdtool.conf"), filepath.Join(dir, ".cmdtool.conf.test"))
+		if err != nil {
+			t.Errorf("applyConfig() with %q: unexpected error: %v", tt.arguments, err)
+			continue
+		}
+		if *logfile != tt.want || *loglevel != "warn" {
+			t.Errorf("applyConfig() with %q: logfile %q, loglevel %q, want %q, %q", tt.arguments,
+				*logfile, *loglevel, tt.want, "warn")
+		}
+	}
+}
+
+// The shared startup file skips the commands and prompt fields of other tools
+func TestRunStartupFilesShared(t *testing.T) {
+
+	dir, remove := configTestDir(t, map[string]string{
+		".cmdtoolrc": "pin add x\nprompt '{peer}> '\nset x 1\n",
+	})
+	defer remove()
+	home := os.Getenv("HOME")
+	defer func() {
+		_ = os.Setenv("HOME", home)
+	}()
+	_ = os.Setenv("HOME", dir)
+
+	c, out := newTestCommander("")
+	c.runStartupFiles()
+	if out.Len() > 0 {
+		t.Errorf("runStartupFiles() printed %q, want nothing", out.String())
+	}
+	if x, _ := c.getVariable("x"); x != "1" {
+		t.Errorf("variable x = %q after the startup files, want %q", x, "1")
+	}
+	if c.promptFormat != defaultPromptFormat {
+		t.Errorf("prompt format = %q, want %q", c.promptFormat, defaultPromptFormat)
+	}
+
+	// Other commands are unknown again afterwards
+	if c.executeCommand("pin add x") {
+		t.Errorf("executeCommand(%q) succeeded after the startup files", "pin add x")
+	}
+}
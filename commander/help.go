@@ -0,0 +1,358 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Categories of the built-in commands, listed in this order by the help, followed by the ones of the tool
+const (
+	CategoryCommander = "Commander"
+	CategoryScripting = "Scripting"
+	CategoryDeveloper = "Developer"
+
+	// Functions defined by scripts, listed last
+	CategoryFunctions = "Functions"
+)
+
+// CommandFlag is a flag of a command with its description
+type CommandFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Return the first sentence of the description of the command
+func (command *Command) summary() string {
+
+	summary := strings.SplitN(command.Description, "\n", 2)[0]
+	if i := strings.Index(summary, ". "); i >= 0 {
+		summary = summary[:i+1]
+	}
+	return strings.TrimSuffix(summary, ".")
+}
+
+// Return the categories in the order of the help
+func sortCategories(categories []string) {
+
+	rank := func(category string) int {
+		switch category {
+		case CategoryCommander:
+			return 0
+		case CategoryScripting:
+			return 1
+		case CategoryDeveloper:
+			return 2
+		case CategoryFunctions:
+			return 4
+		}
+		return 3
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if rank(categories[i]) != rank(categories[j]) {
+			return rank(categories[i]) < rank(categories[j])
+		}
+		return categories[i] < categories[j]
+	})
+}
+
+// Return the commands by category and the categories in the order of the help, without functions unless asked for
+func (c *Commander) commandsByCategory(withFunctions bool) (map[string][]*Command, []string) {
+
+	byCategory := make(map[string][]*Command)
+	var categories []string
+	for _, name := range c.commandNames() {
+		command, _ := c.lookupCommand(name)
+		if command.Category == CategoryFunctions && !withFunctions {
+			continue
+		}
+		if _, ok := byCategory[command.Category]; !ok {
+			categories = append(categories, command.Category)
+		}
+		byCategory[command.Category] = append(byCategory[command.Category], command)
+	}
+	sortCategories(categories)
+	return byCategory, categories
+}
+
+// A command in the help index
+type helpEntry struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+}
+
+// The commands of a category in the help index
+type helpCategory struct {
+	Category string      `json:"category"`
+	Commands []helpEntry `json:"commands"`
+}
+
+// The help index, i.e. the commands by category
+type helpIndex []helpCategory
+
+func (index helpIndex) String() string {
+
+	width := 0
+	for _, category := range index {
+		for _, entry := range category.Commands {
+			if len(entry.Name) > width {
+				width = len(entry.Name)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i, category := range index {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(category.Category + ":\n")
+		for _, entry := range category.Commands {
+			sb.WriteString(fmt.Sprintf("  %-*s  %s\n", width, entry.Name, entry.Summary))
+		}
+	}
+	sb.WriteString("\nUse 'help <command>' for the help of a command.")
+	return sb.String()
+}
+
+// Return the help index of all commands
+func (c *Commander) helpIndex() helpIndex {
+
+	byCategory, categories := c.commandsByCategory(true)
+
+	index := helpIndex{}
+	for _, category := range categories {
+		entries := []helpEntry{}
+		for _, command := range byCategory[category] {
+			entries = append(entries, helpEntry{Name: command.Name, Summary: command.summary()})
+		}
+		index = append(index, helpCategory{Category: category, Commands: entries})
+	}
+	return index
+}
+
+// The help of a command
+type commandHelp struct {
+	Name        string        `json:"name"`
+	Category    string        `json:"category"`
+	Synopsis    string        `json:"synopsis"`
+	Description string        `json:"description"`
+	Flags       []CommandFlag `json:"flags"`
+	Examples    []string      `json:"examples"`
+}
+
+func (h commandHelp) String() string {
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Usage: %s\n\n%s\n", h.Synopsis, h.Description))
+	if len(h.Flags) > 0 {
+		width := 0
+		for _, f := range h.Flags {
+			if len(f.Name) > width {
+				width = len(f.Name)
+			}
+		}
+		sb.WriteString("\nFlags:\n")
+		for _, f := range h.Flags {
+			sb.WriteString(fmt.Sprintf("  %-*s  %s\n", width, f.Name, f.Description))
+		}
+	}
+	if len(h.Examples) > 0 {
+		sb.WriteString("\nExamples:\n")
+		for _, example := range h.Examples {
+			sb.WriteString("  " + example + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Return the help of the command
+func newCommandHelp(command *Command) commandHelp {
+
+	h := commandHelp{
+		Name:        command.Name,
+		Category:    command.Category,
+		Synopsis:    command.Synopsis,
+		Description: command.Description,
+		Flags:       command.Flags,
+		Examples:    command.Examples,
+	}
+	if h.Flags == nil {
+		h.Flags = []CommandFlag{}
+	}
+	if h.Examples == nil {
+		h.Examples = []string{}
+	}
+	return h
+}
+
+func (c *Commander) helpCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	switch len(arguments) {
+	case 0:
+		return c.helpIndex(), nil
+
+	case 1:
+		if command, ok := c.lookupCommand(arguments[0]); ok {
+			return newCommandHelp(command), nil
+		}
+		if expansion, ok := c.lookupAlias(arguments[0]); ok {
+			return aliasList{arguments[0]: expansion}, nil
+		}
+		return nil, &unknownCommandError{arguments[0], c.suggestCommands(arguments[0])}
+
+	default:
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'help [command]'")
+	}
+}
+
+// Maximum number of similar commands suggested for an unknown one
+const maxSuggestions = 3
+
+// Return the commands and aliases similar to the unknown name, the most similar first
+//
+// Similar names differ by a few edits, i.e. by one or a third of the letters at most, or start with the name.
+func (c *Commander) suggestCommands(name string) []string {
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := len(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	var candidates []candidate
+	seen := map[string]bool{name: true}
+	for _, other := range append(c.commandNames(), c.aliasNames()...) {
+
+		// Skip the name itself, e.g. an alias expanded to an unknown command, and names of commands and aliases twice
+		if seen[other] {
+			continue
+		}
+		seen[other] = true
+
+		distance := editDistance(name, other)
+		if distance <= maxDistance || (len(name) > 1 && strings.HasPrefix(other, name)) {
+			candidates = append(candidates, candidate{other, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var suggestions []string
+	for _, candidate := range candidates {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, candidate.name)
+	}
+	return suggestions
+}
+
+// Return the number of insertions, deletions, substitutions and transpositions of adjacent letters turning a into b
+func editDistance(a, b string) int {
+
+	s, t := []rune(a), []rune(b)
+
+	// Rows of the distances between the prefixes of s and t
+	before, previous, current := make([]int, len(t)+1), make([]int, len(t)+1), make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] && before[j-2]+1 < current[j] {
+				current[j] = before[j-2] + 1
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+	return previous[len(t)]
+}
+
+// Return the reference of the commands, except functions, as Markdown
+func (c *Commander) markdownReference() string {
+
+	byCategory, categories := c.commandsByCategory(false)
+
+	var sb strings.Builder
+	sb.WriteString("# Commands\n\n<!-- Generated by 'docs', do not edit -->\n")
+
+	// Index with links to the commands
+	for _, category := range categories {
+		sb.WriteString(fmt.Sprintf("\n**%s**:", category))
+		for i, command := range byCategory[category] {
+			separator := ","
+			if i == 0 {
+				separator = ""
+			}
+			sb.WriteString(fmt.Sprintf("%s [%s](#%s)", separator, command.Name, command.Name))
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, category := range categories {
+		sb.WriteString(fmt.Sprintf("\n## %s\n", category))
+		for _, command := range byCategory[category] {
+			sb.WriteString(fmt.Sprintf("\n### %s\n\n```\n%s\n```\n\n%s\n", command.Name, command.Synopsis, command.Description))
+			if len(command.Flags) > 0 {
+				sb.WriteString("\n| Flag | Description |\n| --- | --- |\n")
+				for _, f := range command.Flags {
+					sb.WriteString(fmt.Sprintf("| `%s` | %s |\n", f.Name, f.Description))
+				}
+			}
+			if len(command.Examples) > 0 {
+				sb.WriteString("\n```\n" + strings.Join(command.Examples, "\n") + "\n```\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (c *Commander) docsCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) > 1 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'docs [file]'")
+	}
+
+	reference := c.markdownReference()
+	if len(arguments) == 0 {
+		return reference, nil
+	}
+
+	err := ioutil.WriteFile(arguments[0], []byte(reference), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.WriteFile: %v", err)
+	}
+	SetResult(ctx, arguments[0])
+	return nil, nil
+}
+
+// Completer of the help command
+func (c *Commander) helpCompleter(arguments []string) []string {
+
+	if len(arguments) == 1 {
+		return CompleteWords(arguments[0], append(c.commandNames(), c.aliasNames()...)...)
+	}
+	return nil
+}
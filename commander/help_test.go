@@ -0,0 +1,178 @@
+package commander
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Define the function greet by a script in the directory
+func defineGreet(t *testing.T, c *Commander, dir string) {
+
+	filename := filepath.Join(dir, "greet.cmd")
+	err := ioutil.WriteFile(filename, []byte("func greet\n\techo hi\nend\n"), 0600)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile(): %v", err)
+	}
+	if !c.executeCommand("execute " + filename) {
+		t.Fatalf("execute %s failed", filename)
+	}
+}
+
+func TestHelp(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-help")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	c, out := newTestCommander("")
+	defineGreet(t, c, dir)
+	c.Register(Command{Name: "add", Category: "IPFS", Synopsis: "add file...", Description: "Adds the files. Details.",
+		Handler: func(ctx context.Context, arguments []string) (interface{}, error) {
+			return nil, nil
+		}})
+
+	// The index lists the categories in order, functions last
+	out.Reset()
+	if !c.executeCommand("help") {
+		t.Fatalf("help failed: %q", out.String())
+	}
+	index := out.String()
+	last := -1
+	for _, want := range []string{"Commander:\n", "Scripting:\n", "Developer:\n", "IPFS:\n  add      Adds the files\n", "Functions:\n  greet"} {
+		i := strings.Index(index, want)
+		if i <= last {
+			t.Errorf("help printed %q, missing %q in order", index, want)
+		}
+		last = i
+	}
+
+	tests := []struct {
+		line   string
+		wantOK bool
+		want   []string
+	}{
+		{"help history", true, []string{"Usage: history [-n number] [filter]\n\nLists the command lines", "Flags:\n  -n number  lists", "Examples:\n  history -n 10 add"}},
+		{"help greet", true, []string{"Usage: greet [arguments]", "Function defined in"}},
+		{"alias hi = 'echo hi'; help hi", true, []string{"hi = echo hi"}},
+		{"help unknown", false, []string{"error: unknown command \"unknown\""}},
+		{"help a b", false, []string{"wrong input"}},
+	}
+
+	for _, tt := range tests {
+		out.Reset()
+		if ok := c.executeCommand(tt.line); ok != tt.wantOK {
+			t.Errorf("executeCommand(%q) = %v, want %v, output %q", tt.line, ok, tt.wantOK, out.String())
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("executeCommand(%q) printed %q, missing %q", tt.line, out.String(), want)
+			}
+		}
+	}
+}
+
+// Suggestions are similar names, but never the name itself
+func TestSuggestCommands(t *testing.T) {
+
+	c, out := newTestCommander("")
+	if !c.executeCommand("alias a = b; alias b = a; alias echo = 'echo said'") {
+		t.Fatalf("alias failed: %q", out.String())
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"a", []string{"b"}},
+		{"echo", nil},
+		{"ech", []string{"echo"}},
+		{"help", nil},
+	}
+
+	for _, tt := range tests {
+		got := c.suggestCommands(tt.name)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestCommands(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	for _, name := range append(c.commandNames(), c.aliasNames()...) {
+		for _, suggestion := range c.suggestCommands(name) {
+			if suggestion == name {
+				t.Errorf("suggestCommands(%q) = %q, contains the name", name, c.suggestCommands(name))
+			}
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"pin", "pin", 0},
+		{"pinn", "pin", 1},
+		{"pn", "pin", 1},
+		{"pon", "pin", 1},
+		{"histroy", "history", 1},
+		{"", "add", 3},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-docs")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	filename := filepath.Join(dir, "COMMANDS.md")
+
+	c, out := newTestCommander("")
+	defineGreet(t, c, dir)
+	if !c.executeCommand("docs " + filename) {
+		t.Fatalf("docs failed: %q", out.String())
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): %v", err)
+	}
+	reference := string(b)
+	for _, want := range []string{
+		"**Commander**: [alias](#alias),",
+		"\n## Scripting\n",
+		"\n### set\n\n```\nset [name [value]]|-e|+e\n```\n\nSets the variable",
+		"| `-e` | stops scripts at the first failing command |\n",
+		"```\nset name world\nset -e\n```\n",
+	} {
+		if !strings.Contains(reference, want) {
+			t.Errorf("docs wrote %q, missing %q", reference, want)
+		}
+	}
+	if strings.Contains(reference, "greet") {
+		t.Errorf("docs wrote %q with function %q", reference, "greet")
+	}
+}
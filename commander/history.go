@@ -0,0 +1,189 @@
+package commander
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Load the history of the former sessions with the same name and save it from now on
+func (c *Commander) historyInit() error {
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("os.UserHomeDir: %v", err)
+	}
+	c.historyFilename = filepath.Join(home, fmt.Sprintf(".cmdtool_%s_history", c.name))
+
+	file, err := os.Open(c.historyFilename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("os.Open: %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); len(strings.TrimSpace(line)) > 0 {
+			c.appendHistory(line)
+			lines++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Sessions append to the file, which is trimmed to the history kept here
+	if lines > len(c.historyLines()) {
+		return c.saveHistory()
+	}
+	return nil
+}
+
+// Append the command line to the history, removing a former duplicate and the oldest lines exceeding the size
+func (c *Commander) appendHistory(line string) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	for i, h := range c.history {
+		if h == line {
+			c.history = append(c.history[:i], c.history[i+1:]...)
+			break
+		}
+	}
+	c.history = append(c.history, line)
+
+	if len(c.history) > c.historySize {
+		c.history = c.history[len(c.history)-c.historySize:]
+	}
+}
+
+// Return the command lines of the history, the latest last
+func (c *Commander) historyLines() []string {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return append([]string{}, c.history...)
+}
+
+// Save the history, replacing the file
+func (c *Commander) saveHistory() error {
+
+	content := strings.Join(c.historyLines(), "\n") + "\n"
+	err := ioutil.WriteFile(c.historyFilename, []byte(content), 0600)
+	if err != nil {
+		return fmt.Errorf("ioutil.WriteFile: %v", err)
+	}
+	return nil
+}
+
+// Append the command line to the history and to its file, i.e. other sessions with the same name keep their lines
+func (c *Commander) addHistory(line string) error {
+
+	c.appendHistory(line)
+
+	if len(c.historyFilename) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(c.historyFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %v", err)
+	}
+	_, err = file.WriteString(line + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error appending to history: %v", err)
+	}
+	return nil
+}
+
+// Expand a command line starting with '!' to the referenced one of the history
+//
+// '!!' is the last command line, '!n' the n-th, '!-n' the n-th last and '!prefix' the last one starting with prefix.
+func (c *Commander) expandHistory(line string) (string, bool, error) {
+
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "!") || len(line) == 1 {
+		return line, false, nil
+	}
+	reference := line[1:]
+	history := c.historyLines()
+
+	if reference == "!" {
+		reference = "-1"
+	}
+
+	if n, err := strconv.Atoi(reference); err == nil {
+		if n < 0 {
+			n = len(history) + 1 + n
+		}
+		if n < 1 || n > len(history) {
+			return "", false, fmt.Errorf("!%s: event not found", line[1:])
+		}
+		return history[n-1], true, nil
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if strings.HasPrefix(history[i], reference) {
+			return history[i], true, nil
+		}
+	}
+	return "", false, fmt.Errorf("!%s: event not found", reference)
+}
+
+// A command line of the history with its number
+type historyEntry struct {
+	Number int    `json:"number"`
+	Line   string `json:"line"`
+}
+
+func (e historyEntry) String() string {
+	return fmt.Sprintf("%5d  %s", e.Number, e.Line)
+}
+
+func (c *Commander) historyCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	history := c.historyLines()
+
+	// Limit to the last n entries
+	last := len(history)
+	if len(arguments) > 1 && arguments[0] == "-n" {
+		var err error
+		last, err = strconv.Atoi(arguments[1])
+		if err != nil || last < 0 {
+			return nil, fmt.Errorf("invalid number of entries %q", arguments[1])
+		}
+		arguments = arguments[2:]
+	}
+
+	// Filter by the rest of the arguments
+	filter := strings.Join(arguments, " ")
+
+	var numbers []int
+	for i, line := range history {
+		if strings.Contains(line, filter) {
+			numbers = append(numbers, i+1)
+		}
+	}
+	if len(numbers) > last {
+		numbers = numbers[len(numbers)-last:]
+	}
+
+	entries := make([]historyEntry, len(numbers))
+	for i, n := range numbers {
+		entries[i] = historyEntry{Number: n, Line: history[n-1]}
+	}
+	return entries, nil
+}
@@ -0,0 +1,326 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A command line running in the background with its output buffered
+type job struct {
+	id          int
+	commandline string
+	started     time.Time
+	cancel      context.CancelFunc
+	done        chan struct{}
+	out         *jobOutput
+
+	// Set when done, guarded by mu
+	mu       sync.Mutex
+	status   bool
+	duration time.Duration
+}
+
+// Maximum number of bytes of the output of a job buffered until a writer is attached
+const maxJobOutput = 1 << 20
+
+// The output of a job, which is buffered up to its maximum until a writer is attached
+type jobOutput struct {
+	mu       sync.Mutex
+	buffer   cappedBuffer
+	attached io.Writer
+}
+
+func (o *jobOutput) Write(p []byte) (int, error) {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.attached != nil {
+		return o.attached.Write(p)
+	}
+	return o.buffer.Write(p)
+}
+
+// Write the output buffered so far to w and write the following output directly to it, until detached by nil
+func (o *jobOutput) attach(w io.Writer) error {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.attached = w
+	if w == nil {
+		return nil
+	}
+	_, err := o.buffer.WriteTo(w)
+	if err == nil && o.buffer.dropped > 0 {
+		_, err = fmt.Fprintf(w, "[%d bytes of output dropped]\n", o.buffer.dropped)
+		o.buffer.dropped = 0
+	}
+	return err
+}
+
+// Start the command line as job with its own execution, which inherits the parameters of the calling one
+func (c *Commander) startJob(ctx context.Context, commandline string) *job {
+
+	c.jobsMutex.Lock()
+	c.lastJobID++
+	j := &job{
+		id:          c.lastJobID,
+		commandline: strings.TrimSpace(commandline),
+		started:     time.Now(),
+		done:        make(chan struct{}),
+		out:         &jobOutput{buffer: cappedBuffer{capacity: maxJobOutput}},
+	}
+	c.jobs[j.id] = j
+	c.jobsMutex.Unlock()
+
+	e := newExecution(c, j.out)
+	e.arguments = executionOf(ctx).arguments
+
+	// The job is not cancelled by the end of the command line starting it
+	var jobCtx context.Context
+	jobCtx, j.cancel = context.WithCancel(withExecution(context.Background(), e))
+
+	c.logs.Info("Job started", "job", j.id, "commandline", j.commandline)
+	go func() {
+		status := c.executeChain(jobCtx, j.commandline, func(err error) {
+			_, _ = fmt.Fprintf(j.out, "error: %v\n", err)
+		})
+		j.cancel()
+
+		j.mu.Lock()
+		j.status = status
+		j.duration = time.Since(j.started)
+		j.mu.Unlock()
+		close(j.done)
+
+		c.logs.Info("Job finished", "job", j.id, "commandline", j.commandline, "duration", j.duration, "status", status)
+	}()
+	return j
+}
+
+// Return the job of the id, optionally with a leading '%'
+func (c *Commander) lookupJob(id string) (*job, error) {
+
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "%"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid job id %q", id)
+	}
+
+	c.jobsMutex.Lock()
+	defer c.jobsMutex.Unlock()
+
+	j, ok := c.jobs[n]
+	if !ok {
+		return nil, fmt.Errorf("no job %d", n)
+	}
+	return j, nil
+}
+
+// Return the jobs sorted by id
+func (c *Commander) sortedJobs() []*job {
+
+	c.jobsMutex.Lock()
+	defer c.jobsMutex.Unlock()
+
+	var list []*job
+	for _, j := range c.jobs {
+		list = append(list, j)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].id < list[k].id })
+	return list
+}
+
+// Remove the job, which is done
+func (c *Commander) removeJob(j *job) {
+
+	c.jobsMutex.Lock()
+	defer c.jobsMutex.Unlock()
+
+	delete(c.jobs, j.id)
+}
+
+// Return the state of the job, i.e. running, done or failed
+func (j *job) state() string {
+
+	select {
+	case <-j.done:
+	default:
+		return "running"
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status {
+		return "done"
+	}
+	return "failed"
+}
+
+// Report the jobs done since the last call with their output and remove them, e.g. before the prompt
+func (c *Commander) notifyJobs() {
+
+	for _, j := range c.sortedJobs() {
+		state := j.state()
+		if state == "running" {
+			continue
+		}
+		_, _ = fmt.Fprintf(c.out, "[%d] %s  %s\n", j.id, state, j.commandline)
+		_ = j.out.attach(c.out)
+		c.removeJob(j)
+	}
+}
+
+// A background job as listed by 'jobs'
+type jobInfo struct {
+	ID          int    `json:"id"`
+	State       string `json:"state"`
+	Duration    string `json:"duration"`
+	Commandline string `json:"commandline"`
+}
+
+func (i jobInfo) String() string {
+	return fmt.Sprintf("[%d] %-8s %8s  %s", i.ID, i.State, i.Duration, i.Commandline)
+}
+
+func (c *Commander) jobsCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) > 0 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'jobs'")
+	}
+
+	list := []jobInfo{}
+	for _, j := range c.sortedJobs() {
+		state := j.state()
+
+		j.mu.Lock()
+		duration := j.duration
+		if state == "running" {
+			duration = time.Since(j.started)
+		}
+		j.mu.Unlock()
+
+		list = append(list, jobInfo{
+			ID:          j.id,
+			State:       state,
+			Duration:    duration.Round(time.Millisecond).String(),
+			Commandline: j.commandline,
+		})
+	}
+	return list, nil
+}
+
+func (c *Commander) waitCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	var list []*job
+	if len(arguments) == 0 {
+		list = c.sortedJobs()
+	}
+	for _, id := range arguments {
+		j, err := c.lookupJob(id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, j)
+	}
+
+	// Wait for the jobs in order and print their output
+	failed := 0
+	for _, j := range list {
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		_, _ = fmt.Fprintf(Output(ctx), "[%d] %s  %s\n", j.id, j.state(), j.commandline)
+		err := j.out.attach(Output(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.removeJob(j)
+		if j.state() == "failed" {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return nil, fmt.Errorf("%d of %d jobs failed", failed, len(list))
+	}
+	return nil, nil
+}
+
+func (c *Commander) fgCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) > 1 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'fg [id]'")
+	}
+
+	var j *job
+	if len(arguments) == 1 {
+		var err error
+		j, err = c.lookupJob(arguments[0])
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		list := c.sortedJobs()
+		if len(list) == 0 {
+			return nil, fmt.Errorf("no jobs")
+		}
+		j = list[len(list)-1]
+	}
+
+	// Follow the output until the job ends, an interrupt cancels the job
+	_, _ = fmt.Fprintf(Output(ctx), "[%d] %s\n", j.id, j.commandline)
+	err := j.out.attach(Output(ctx))
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.cancel()
+		<-j.done
+	}
+	_ = j.out.attach(nil)
+	c.removeJob(j)
+
+	if j.state() == "failed" {
+		return nil, errReported
+	}
+	return nil, nil
+}
+
+func (c *Commander) killCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'kill id...'")
+	}
+
+	for _, id := range arguments {
+		j, err := c.lookupJob(id)
+		if err != nil {
+			return nil, err
+		}
+		c.logs.Info("Kill job", "job", j.id, "commandline", j.commandline)
+		j.cancel()
+	}
+	return nil, nil
+}
+
+// Completer of commands with job ids as arguments
+func (c *Commander) jobCompleter(arguments []string) []string {
+
+	var ids []string
+	for _, j := range c.sortedJobs() {
+		ids = append(ids, strconv.Itoa(j.id))
+	}
+	return CompleteWords(arguments[len(arguments)-1], ids...)
+}
@@ -0,0 +1,215 @@
+package commander
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of tokens of a command line
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOperator
+)
+
+// A token of a command line, i.e. a word with quotes and escapes removed or a chaining operator
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// Split a command line into tokens like a shell does
+//
+// Words are separated by white spaces. Single quotes preserve everything literally, double quotes preserve
+// everything but backslash escapes of '"', '\' and '$'. Outside of quotes a backslash escapes any character.
+// A '#' at the beginning of a word starts a comment up to the end of the line. The operators ';', '&&', '||' and
+// '&' are tokens of their own, even without surrounding white spaces.
+//
+// If lookup is not nil, '${name}', '$name', the special variables '$?' and '$_' and the parameters '$0'..'$9'
+// and '$#' are substituted by its result outside of single quotes. Otherwise '$' has no special meaning.
+func tokenize(line string, lookup func(name string) string) ([]token, error) {
+	return lex(line, lookup, false)
+}
+
+// Split the items of 'for' into words like tokenize, but split substitutions outside of quotes at white spaces
+//
+// An empty substitution outside of quotes is no word, e.g. of an empty list.
+func splitWords(line string, lookup func(name string) string) ([]string, error) {
+
+	tokens, err := lex(line, lookup, true)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		words[i] = t.value
+	}
+	return words, nil
+}
+
+// Split a command line into tokens, substitutions outside of quotes into words as well, if split is set
+func lex(line string, lookup func(name string) string, split bool) ([]token, error) {
+
+	var (
+		tokens []token
+		word   strings.Builder
+		inWord bool
+		start  int
+	)
+
+	endWord := func() {
+		if inWord {
+			tokens = append(tokens, token{tokenWord, word.String(), start})
+			word.Reset()
+			inWord = false
+		}
+	}
+	beginWord := func(i int) {
+		if !inWord {
+			inWord = true
+			start = i
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			endWord()
+
+		case c == '#' && !inWord:
+			return tokens, nil
+
+		case c == ';':
+			endWord()
+			tokens = append(tokens, token{tokenOperator, chainSequence, i})
+
+		case strings.HasPrefix(line[i:], chainAnd) || strings.HasPrefix(line[i:], chainOr):
+			endWord()
+			tokens = append(tokens, token{tokenOperator, line[i : i+2], i})
+			i++
+
+		case c == '&':
+			endWord()
+			tokens = append(tokens, token{tokenOperator, chainBackground, i})
+
+		case c == '\\':
+			if i+1 == len(line) {
+				return nil, fmt.Errorf("trailing backslash at position %d", i)
+			}
+			beginWord(i)
+			i++
+			word.WriteByte(line[i])
+
+		case c == '\'':
+			beginWord(i)
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote at position %d", i)
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+
+		case c == '$' && lookup != nil && split:
+			var value strings.Builder
+			n, err := expandVariable(line[i:], lookup, &value)
+			if err != nil {
+				return nil, fmt.Errorf("%v at position %d", err, i)
+			}
+			for _, b := range []byte(value.String()) {
+				if strings.IndexByte(" \t\n\r", b) >= 0 {
+					endWord()
+				} else {
+					beginWord(i)
+					word.WriteByte(b)
+				}
+			}
+			i += n - 1
+
+		case c == '$' && lookup != nil:
+			beginWord(i)
+			n, err := expandVariable(line[i:], lookup, &word)
+			if err != nil {
+				return nil, fmt.Errorf("%v at position %d", err, i)
+			}
+			i += n - 1
+
+		case c == '"':
+			beginWord(i)
+			quote := i
+			for i++; ; i++ {
+				if i == len(line) {
+					return nil, fmt.Errorf("unterminated double quote at position %d", quote)
+				}
+				if line[i] == '"' {
+					break
+				}
+				if line[i] == '$' && lookup != nil {
+					n, err := expandVariable(line[i:], lookup, &word)
+					if err != nil {
+						return nil, fmt.Errorf("%v at position %d", err, i)
+					}
+					i += n - 1
+					continue
+				}
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$", line[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+
+		default:
+			beginWord(i)
+			word.WriteByte(c)
+		}
+	}
+	endWord()
+
+	return tokens, nil
+}
+
+// Write the value of the variable referenced at the beginning of s and return the number of bytes consumed
+func expandVariable(s string, lookup func(name string) string, word *strings.Builder) (int, error) {
+
+	// Braced variable name
+	if strings.HasPrefix(s, "${") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated variable reference")
+		}
+		if end == 2 {
+			return 0, fmt.Errorf("empty variable reference")
+		}
+		word.WriteString(lookup(s[2:end]))
+		return end + 1, nil
+	}
+
+	// Special variable or parameter
+	if len(s) > 1 && (s[1] == '?' || s[1] == '#' || (s[1] >= '0' && s[1] <= '9') ||
+		(s[1] == '_' && !(len(s) > 2 && isNameByte(s[2])))) {
+		word.WriteString(lookup(s[1:2]))
+		return 2, nil
+	}
+
+	// Plain variable name
+	n := 1
+	for n < len(s) && isNameByte(s[n]) {
+		n++
+	}
+	if n == 1 {
+
+		// A lonely '$' is taken literally
+		word.WriteByte('$')
+		return 1, nil
+	}
+	word.WriteString(lookup(s[1:n]))
+	return n, nil
+}
+
+// Check for a byte allowed in variable names
+func isNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,248 @@
+package commander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"echo hello world", []string{"echo", "hello", "world"}},
+		{"  echo \t hello  ", []string{"echo", "hello"}},
+		{`echo "hello   world"`, []string{"echo", "hello   world"}},
+		{`echo 'hello   world'`, []string{"echo", "hello   world"}},
+		{`echo 'a "b" c'`, []string{"echo", `a "b" c`}},
+		{`echo "a 'b' c"`, []string{"echo", "a 'b' c"}},
+		{`echo 'a\ b'`, []string{"echo", `a\ b`}},
+		{`echo "a\"b"`, []string{"echo", `a"b`}},
+		{`echo "a\\b"`, []string{"echo", `a\b`}},
+		{`echo "a\nb"`, []string{"echo", `a\nb`}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo \'a\'`, []string{"echo", "'a'"}},
+		{`echo pre"mid dle"post`, []string{"echo", "premid dlepost"}},
+		{`echo "" ''`, []string{"echo", "", ""}},
+		{"# comment", nil},
+		{"echo a # comment", []string{"echo", "a"}},
+		{"echo a#b", []string{"echo", "a#b"}},
+		{`echo "#" \#`, []string{"echo", "#", "#"}},
+		{"echo a; echo b", []string{"echo", "a", ";", "echo", "b"}},
+		{"echo a;echo b", []string{"echo", "a", ";", "echo", "b"}},
+		{"add f.txt && pin ls", []string{"add", "f.txt", "&&", "pin", "ls"}},
+		{"a||b", []string{"a", "||", "b"}},
+		{`echo "a; b && c || d"`, []string{"echo", "a; b && c || d"}},
+		{`echo a\;b`, []string{"echo", "a;b"}},
+		{"sleep 10 &", []string{"sleep", "10", "&"}},
+		{"echo a&", []string{"echo", "a", "&"}},
+		{`echo "a & b" \&`, []string{"echo", "a & b", "&"}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := tokenize(tt.line, nil)
+		if err != nil {
+			t.Errorf("tokenize(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		var got []string
+		for _, token := range tokens {
+			got = append(got, token.value)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeOperators(t *testing.T) {
+
+	tests := []struct {
+		line string
+		want []tokenKind
+	}{
+		{"a; b", []tokenKind{tokenWord, tokenOperator, tokenWord}},
+		{"a && b || c", []tokenKind{tokenWord, tokenOperator, tokenWord, tokenOperator, tokenWord}},
+		{`a ";" '&&'`, []tokenKind{tokenWord, tokenWord, tokenWord}},
+		{`a \|\|`, []tokenKind{tokenWord, tokenWord}},
+		{"a && b &", []tokenKind{tokenWord, tokenOperator, tokenWord, tokenOperator}},
+		{`a \&`, []tokenKind{tokenWord, tokenWord}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := tokenize(tt.line, nil)
+		if err != nil {
+			t.Errorf("tokenize(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		var got []tokenKind
+		for _, token := range tokens {
+			got = append(got, token.kind)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) kinds = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+
+	tests := []string{
+		`echo "unterminated`,
+		`echo 'unterminated`,
+		`echo trailing\`,
+		`echo "escaped end\"`,
+	}
+
+	for _, line := range tests {
+		if _, err := tokenize(line, nil); err == nil {
+			t.Errorf("tokenize(%q): expected error", line)
+		}
+	}
+}
+
+func TestTokenizeVariables(t *testing.T) {
+
+	values := map[string]string{
+		"cid":   "QmHash",
+		"empty": "",
+		"space": "a b",
+		"_":     "result",
+		"?":     "0",
+		"#":     "2",
+		"0":     "script.cmd",
+		"1":     "first",
+		"12":    "twelfth",
+	}
+	lookup := func(name string) string {
+		return values[name]
+	}
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"cat ${cid}", []string{"cat", "QmHash"}},
+		{"cat $cid", []string{"cat", "QmHash"}},
+		{"cat /ipfs/${cid}/file", []string{"cat", "/ipfs/QmHash/file"}},
+		{"echo $cid.txt", []string{"echo", "QmHash.txt"}},
+		{`echo "${cid} ${space}"`, []string{"echo", "QmHash a b"}},
+		{"echo ${space}", []string{"echo", "a b"}},
+		{"echo '${cid}'", []string{"echo", "${cid}"}},
+		{`echo \${cid} "\$cid"`, []string{"echo", "${cid}", "$cid"}},
+		{"echo $_ $?", []string{"echo", "result", "0"}},
+		{"echo $_x", []string{"echo", ""}},
+		{"echo x${empty}y", []string{"echo", "xy"}},
+		{"echo $ 5$", []string{"echo", "$", "5$"}},
+		{"echo $0 $# $1 $2", []string{"echo", "script.cmd", "2", "first", ""}},
+		{"echo $12 ${12}", []string{"echo", "first2", "twelfth"}},
+		{"echo $#x #x", []string{"echo", "2x"}},
+		{"echo ${unknown}", []string{"echo", ""}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := tokenize(tt.line, lookup)
+		if err != nil {
+			t.Errorf("tokenize(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		var got []string
+		for _, token := range tokens {
+			got = append(got, token.value)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"echo ${cid", "echo ${}"} {
+		if _, err := tokenize(line, lookup); err == nil {
+			t.Errorf("tokenize(%q): expected error", line)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+
+	values := map[string]string{
+		"list":  "a b\tc",
+		"blank": " x ",
+		"empty": "",
+	}
+	lookup := func(name string) string {
+		return values[name]
+	}
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"$list", []string{"a", "b", "c"}},
+		{"${list} d", []string{"a", "b", "c", "d"}},
+		{`"$list" '$list'`, []string{"a b\tc", "$list"}},
+		{"x$list", []string{"xa", "b", "c"}},
+		{"${list}x", []string{"a", "b", "cx"}},
+		{"y${blank}z", []string{"y", "x", "z"}},
+		{"$empty", []string{}},
+		{`"$empty"`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		got, err := splitWords(tt.line, lookup)
+		if err != nil {
+			t.Errorf("splitWords(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandline(t *testing.T) {
+
+	tests := []struct {
+		line    string
+		want    []chainedCommand
+		wantErr string
+	}{
+		{"a; b", []chainedCommand{{";", "a"}, {";", " b"}}, ""},
+		{"a && b || c", []chainedCommand{{";", "a "}, {"&&", " b "}, {"||", " c"}}, ""},
+		{"a;", []chainedCommand{{";", "a"}, {";", ""}}, ""},
+		{"a &", []chainedCommand{{";", "a "}, {"&", ""}}, ""},
+		{"", []chainedCommand{{";", ""}}, ""},
+		{"&& echo b", nil, `syntax error near "&&"`},
+		{"echo a &&", nil, `syntax error near "&&"`},
+		{"echo a || # comment", nil, `syntax error near "||"`},
+		{"|| echo b", nil, `syntax error near "||"`},
+		{"echo a;; echo b", nil, `syntax error near ";"`},
+		{"; echo a", nil, `syntax error near ";"`},
+		{"echo a & &", nil, `syntax error near "&"`},
+		{"echo a && ; echo b", nil, `syntax error near ";"`},
+	}
+
+	for _, tt := range tests {
+		tokens, err := tokenize(tt.line, nil)
+		if err != nil {
+			t.Errorf("tokenize(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		got, err := splitCommandline(tt.line, tokens)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("splitCommandline(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitCommandline(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandline(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package commander
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels of logging in increasing severity
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (level logLevel) String() string {
+	return levelNames[level]
+}
+
+// Parse the name of a level
+func parseLogLevel(name string) (logLevel, error) {
+
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return logLevel(i), nil
+		}
+	}
+	return levelInfo, fmt.Errorf("unknown log level %q, use one of %s", name, strings.Join(levelNames, ", "))
+}
+
+// Logger is a leveled logger writing a line per message as text or JSON with the fields given as key-value pairs
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  logLevel
+	asJSON bool
+
+	// Writer of the first error of writing to out, e.g. of a failed rotation, and whether it has been reported
+	errOut   io.Writer
+	reported bool
+}
+
+// Set the writer of the logger
+func (l *Logger) setOutput(out io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = out
+	l.reported = false
+}
+
+// Report the first error of writing to the current writer
+func (l *Logger) reportError(err error) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.reported || l.errOut == nil {
+		return
+	}
+	l.reported = true
+	_, _ = fmt.Fprintf(l.errOut, "error: logging: %v\n", err)
+}
+
+// Set the minimal level of the messages written
+func (l *Logger) setLevel(level logLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// Return the minimal level of the messages written
+func (l *Logger) getLevel() logLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// Return the format, i.e. text or json
+func (l *Logger) format() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.asJSON {
+		return "json"
+	}
+	return "text"
+}
+
+// Switch between JSON and text format
+func (l *Logger) setJSON(asJSON bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.asJSON = asJSON
+}
+
+func (l *Logger) Debug(msg string, fields ...interface{}) { l.output(2, levelDebug, msg, fields) }
+func (l *Logger) Info(msg string, fields ...interface{})  { l.output(2, levelInfo, msg, fields) }
+func (l *Logger) Warn(msg string, fields ...interface{})  { l.output(2, levelWarn, msg, fields) }
+func (l *Logger) Error(msg string, fields ...interface{}) { l.output(2, levelError, msg, fields) }
+
+// Write the message, if its level is enabled, with the caller at the depth of the call stack
+//
+// The writer is not locked by the logger, i.e. it is safe for concurrent use like the logfiles and a rotation does
+// not block other messages.
+func (l *Logger) output(depth int, level logLevel, msg string, fields []interface{}) {
+
+	l.mu.Lock()
+	out, minLevel, asJSON := l.out, l.level, l.asJSON
+	l.mu.Unlock()
+
+	if level < minLevel {
+		return
+	}
+
+	caller := ""
+	if depth > 0 {
+		if _, file, line, ok := runtime.Caller(depth); ok {
+			caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+	}
+	now := time.Now()
+
+	var line string
+	if asJSON {
+		entry := map[string]interface{}{
+			"time":  now.Format(time.RFC3339Nano),
+			"level": level.String(),
+			"msg":   msg,
+		}
+		if len(caller) > 0 {
+			entry["caller"] = caller
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			value := fields[i+1]
+			switch v := value.(type) {
+			case error:
+				value = v.Error()
+			case fmt.Stringer:
+				value = v.String()
+			}
+			entry[fmt.Sprint(fields[i])] = value
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			b = []byte(fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error()))
+		}
+		line = string(b) + "\n"
+	} else {
+		var sb strings.Builder
+		sb.WriteString(now.Format("2006/01/02 15:04:05.000"))
+		sb.WriteString(" " + strings.ToUpper(level.String()))
+		if len(caller) > 0 {
+			sb.WriteString(" " + caller + ":")
+		}
+		sb.WriteString(" " + msg)
+		for i := 0; i+1 < len(fields); i += 2 {
+			value := fmt.Sprint(fields[i+1])
+			if strings.ContainsAny(value, " \t\n\"=") || len(value) == 0 {
+				value = fmt.Sprintf("%q", value)
+			}
+			sb.WriteString(fmt.Sprintf(" %v=%s", fields[i], value))
+		}
+		line = sb.String() + "\n"
+	}
+
+	_, err := io.WriteString(out, line)
+	if err != nil {
+		l.reportError(err)
+	}
+}
+
+// Writer for the standard logger used by other packages, which logs each line as message of level info
+type stdLogWriter struct {
+	l *Logger
+}
+
+func (w stdLogWriter) Write(p []byte) (int, error) {
+	w.l.output(0, levelInfo, strings.TrimRight(string(p), "\n"), nil)
+	return len(p), nil
+}
+
+// Rotation of logfiles by size in bytes and age, keeping a number of compressed old files
+type logRotation struct {
+	maxSize  int64
+	maxAge   time.Duration
+	retain   int
+	compress bool
+}
+
+// Start logging to the file, by default named after the session and time, and keep the former one to return to
+func (c *Commander) startLogging(logname string) (*rotatingFile, error) {
+
+	logfilename := logname
+	if len(logfilename) == 0 {
+
+		// Prepare logfile for logging
+		year, month, day := time.Now().Date()
+		hour, minute, second := time.Now().Clock()
+		logfilename = fmt.Sprintf("cmdtool-%s-%v%02d%02d%02d%02d%02d.log", c.name,
+			year, int(month), int(day), int(hour), int(minute), int(second))
+	}
+	logfile, err := openRotatingFile(logfilename, c.rotation.maxSize, c.rotation.maxAge, c.rotation.retain, c.rotation.compress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Switch logging to logfile
+	c.logMutex.Lock()
+	defer c.logMutex.Unlock()
+	c.logStack = append(c.logStack, logfile)
+	c.logs.setOutput(logfile)
+
+	return logfile, nil
+}
+
+// Stop logging to the current file and return to the former one, if any
+func (c *Commander) stopLogging() error {
+
+	c.logMutex.Lock()
+	defer c.logMutex.Unlock()
+
+	if len(c.logStack) == 0 {
+		return fmt.Errorf("logging is off")
+	}
+	logfile := c.logStack[len(c.logStack)-1]
+	c.logStack = c.logStack[:len(c.logStack)-1]
+
+	if len(c.logStack) > 0 {
+		c.logs.setOutput(c.logStack[len(c.logStack)-1])
+	} else {
+		c.logs.setOutput(ioutil.Discard)
+	}
+
+	return logfile.Close()
+}
+
+// Return the name of the current logfile or an empty string, if logging is off
+func (c *Commander) currentLogfile() string {
+
+	c.logMutex.Lock()
+	defer c.logMutex.Unlock()
+
+	if len(c.logStack) == 0 {
+		return ""
+	}
+	return c.logStack[len(c.logStack)-1].Name()
+}
+
+// Return the names of the logfiles, the current one first
+func (c *Commander) logfiles() []string {
+
+	c.logMutex.Lock()
+	defer c.logMutex.Unlock()
+
+	files := []string{}
+	for i := len(c.logStack) - 1; i >= 0; i-- {
+		files = append(files, c.logStack[i].Name())
+	}
+	return files
+}
+
+// Return the number of logfiles in the stack
+func (c *Commander) logDepth() int {
+
+	c.logMutex.Lock()
+	defer c.logMutex.Unlock()
+
+	return len(c.logStack)
+}
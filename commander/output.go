@@ -0,0 +1,298 @@
+package commander
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Formats of the results of commands
+var outputFormats = []string{"plain", "json", "yaml", "table"}
+
+// Check the name of a format
+func checkOutputFormat(format string) error {
+
+	for _, f := range outputFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, use one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// Render the result of a command in the format
+//
+// Plain is meant for humans: a fmt.Stringer is printed by its String method, a slice element by element and
+// other structured values as YAML. JSON and YAML use the JSON field names of structs. A table has a row per
+// element of a slice or per field of a struct or a map.
+func renderResult(w io.Writer, format string, result interface{}) error {
+
+	switch format {
+	case "plain":
+		return renderPlain(w, result)
+
+	case "json":
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("json.MarshalIndent(): %v", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+
+	case "yaml":
+		return renderYAML(w, result)
+
+	case "table":
+		return renderTable(w, result)
+
+	default:
+		return checkOutputFormat(format)
+	}
+}
+
+func renderPlain(w io.Writer, result interface{}) error {
+
+	switch r := result.(type) {
+	case fmt.Stringer:
+		_, err := fmt.Fprintln(w, r.String())
+		return err
+
+	case string:
+		if !strings.HasSuffix(r, "\n") {
+			r += "\n"
+		}
+		_, err := io.WriteString(w, r)
+		return err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(result))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := renderPlain(w, v.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map, reflect.Struct, reflect.Interface:
+		return renderYAML(w, result)
+
+	default:
+		_, err := fmt.Fprintln(w, result)
+		return err
+	}
+}
+
+func renderYAML(w io.Writer, result interface{}) error {
+
+	generic, err := toGeneric(result)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(generic)
+	if err != nil {
+		return fmt.Errorf("yaml.Marshal(): %v", err)
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func renderTable(w io.Writer, result interface{}) error {
+
+	generic, err := toGeneric(result)
+	if err != nil {
+		return err
+	}
+
+	var header []string
+	var rows [][]string
+
+	v := reflect.Indirect(reflect.ValueOf(result))
+	switch elements := generic.(type) {
+	case []interface{}:
+
+		// A row per element with the fields of structs or the keys of maps as columns
+		var columns []string
+		if t := v.Type().Elem(); t.Kind() == reflect.Struct || (t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct) {
+			columns = fieldNames(t)
+		} else {
+			keys := make(map[string]bool)
+			for _, element := range elements {
+				if m, ok := element.(map[string]interface{}); ok {
+					for key := range m {
+						keys[key] = true
+					}
+				}
+			}
+			for key := range keys {
+				columns = append(columns, key)
+			}
+			sort.Strings(columns)
+		}
+
+		if len(columns) == 0 {
+			header = []string{"VALUE"}
+			for _, element := range elements {
+				rows = append(rows, []string{cellString(element)})
+			}
+			break
+		}
+		for _, column := range columns {
+			header = append(header, strings.ToUpper(column))
+		}
+		for _, element := range elements {
+			m, _ := element.(map[string]interface{})
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				row[i] = cellString(m[column])
+			}
+			rows = append(rows, row)
+		}
+
+	case map[string]interface{}:
+
+		// A row per field of a struct or per key of a map
+		var keys []string
+		if v.Kind() == reflect.Struct {
+			keys = fieldNames(v.Type())
+		} else {
+			for key := range elements {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+		header = []string{"KEY", "VALUE"}
+		for _, key := range keys {
+			if value, ok := elements[key]; ok {
+				rows = append(rows, []string{key, cellString(value)})
+			}
+		}
+
+	default:
+		return renderPlain(w, result)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Return the JSON names of the exported fields of the struct type in their order
+func fieldNames(t reflect.Type) []string {
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if len(tag) > 0 {
+			name = tag
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Return the value of a table cell, i.e. scalars as text and everything else as JSON
+func cellString(value interface{}) string {
+
+	switch value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(value)
+		return string(b)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Convert the result into maps, slices and scalars by its JSON encoding, keeping integers as integers
+func toGeneric(result interface{}) (interface{}, error) {
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(): %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	if err != nil {
+		return nil, fmt.Errorf("json.Decode(): %v", err)
+	}
+	return convertNumbers(generic), nil
+}
+
+func convertNumbers(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = convertNumbers(element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = convertNumbers(element)
+		}
+	}
+	return value
+}
+
+// Return the format the results of commands are rendered in
+func (c *Commander) getOutputFormat() string {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return c.outputFormat
+}
+
+func (c *Commander) outputCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	// Show the current format
+	if len(arguments) == 0 {
+		return c.getOutputFormat(), nil
+	}
+	if len(arguments) > 1 {
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'output [%s]'", strings.Join(outputFormats, "|"))
+	}
+
+	err := checkOutputFormat(arguments[0])
+	if err != nil {
+		return nil, err
+	}
+	c.stateMutex.Lock()
+	c.logs.Info("Switch output format by command", "from", c.outputFormat, "to", arguments[0])
+	c.outputFormat = arguments[0]
+	c.stateMutex.Unlock()
+	return nil, nil
+}
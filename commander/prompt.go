@@ -0,0 +1,316 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default templates of the prompt and of the lines of scripts echoed
+const (
+	defaultPromptFormat       = "< {time} {name}> "
+	defaultScriptPromptFormat = "<{time} \"{script}\"> "
+)
+
+// Maximum time to get the values of the fields of a prompt, e.g. from a daemon not responding
+const promptTimeout = time.Second
+
+// PromptField is a field of the prompt templates, i.e. '{name}', with its description and a function returning its
+// current value
+//
+// The value is requested for every prompt showing the field, an error shows '?' instead.
+type PromptField struct {
+	Name        string
+	Description string
+	Value       func(ctx context.Context) (string, error)
+}
+
+// Valid names of fields
+var promptFieldPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// RegisterPromptField adds a new field of the prompt templates or replaces an existing one with the same name
+func (c *Commander) RegisterPromptField(field PromptField) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	c.promptFields[field.Name] = &field
+}
+
+func (c *Commander) promptFieldsInit() {
+
+	c.RegisterPromptField(PromptField{
+		Name:        "time",
+		Description: "the current time, e.g. Jan 2 15:04:05.000",
+		Value: func(ctx context.Context) (string, error) {
+			return time.Now().Format("Jan 2 15:04:05.000"), nil
+		},
+	})
+	c.RegisterPromptField(PromptField{
+		Name:        "name",
+		Description: "the name of the session",
+		Value: func(ctx context.Context) (string, error) {
+			return c.name, nil
+		},
+	})
+	c.RegisterPromptField(PromptField{
+		Name:        "status",
+		Description: "the status of the last command, 0 on success and 1 on failure",
+		Value: func(ctx context.Context) (string, error) {
+			return strconv.Itoa(c.exitStatus()), nil
+		},
+	})
+	c.RegisterPromptField(PromptField{
+		Name:        "script",
+		Description: "the file of the script, in the prompt of scripts only",
+		Value: func(ctx context.Context) (string, error) {
+			return "", nil
+		},
+	})
+}
+
+// A part of a prompt template, i.e. literal text or the name of a field
+type promptSegment struct {
+	text  string
+	field bool
+}
+
+// Parse the template of literal text and fields in braces, e.g. "< {time} {name}> "
+func parsePromptTemplate(template string) ([]promptSegment, error) {
+
+	var segments []promptSegment
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			segments = append(segments, promptSegment{text: template})
+			break
+		}
+		if start > 0 {
+			segments = append(segments, promptSegment{text: template[:start]})
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("field %q not closed by '}'", template[start:])
+		}
+		name := template[start+1 : start+end]
+		if !promptFieldPattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid field name %q", name)
+		}
+		segments = append(segments, promptSegment{text: name, field: true})
+		template = template[start+end+1:]
+	}
+	return segments, nil
+}
+
+// Return the names of the fields in sorted order
+func (c *Commander) promptFieldNames() []string {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	var names []string
+	for name := range c.promptFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the field of the name
+func (c *Commander) lookupPromptField(name string) (*PromptField, bool) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	field, ok := c.promptFields[name]
+	return field, ok
+}
+
+// A field of a template, which is not registered, with the fields registered
+type unknownFieldError struct {
+	name   string
+	fields []string
+}
+
+func (e *unknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field {%s}, use one of {%s}", e.name, strings.Join(e.fields, "}, {"))
+}
+
+// Check the syntax and the fields of the template
+func (c *Commander) checkPromptTemplate(template string) error {
+
+	segments, err := parsePromptTemplate(template)
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if _, ok := c.lookupPromptField(segment.text); segment.field && !ok {
+			return &unknownFieldError{segment.text, c.promptFieldNames()}
+		}
+	}
+	return nil
+}
+
+// Return the template with the fields replaced by the values given or by their current ones
+//
+// Unknown fields are kept as they are.
+func (c *Commander) renderPrompt(template string, values map[string]string) string {
+
+	segments, err := parsePromptTemplate(template)
+	if err != nil {
+		return template
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), promptTimeout)
+	defer cancel()
+
+	var sb strings.Builder
+	for _, segment := range segments {
+		if !segment.field {
+			sb.WriteString(segment.text)
+			continue
+		}
+		if value, ok := values[segment.text]; ok {
+			sb.WriteString(value)
+			continue
+		}
+
+		field, ok := c.lookupPromptField(segment.text)
+		if !ok {
+			sb.WriteString("{" + segment.text + "}")
+			continue
+		}
+		value, err := field.Value(ctx)
+		if err != nil {
+			c.logs.Debug("Prompt field failed", "field", segment.text, "error", err)
+			value = "?"
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[segment.text] = value
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
+
+// Return the template of the prompt and of the lines of scripts echoed
+func (c *Commander) promptFormats() (string, string) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	return c.promptFormat, c.scriptPromptFormat
+}
+
+// Return the prompt at the terminal
+func (c *Commander) prompt() string {
+	promptFormat, _ := c.promptFormats()
+	return c.renderPrompt(promptFormat, nil)
+}
+
+// Return the prompt of the lines of the script echoed
+func (c *Commander) scriptPrompt(scriptname string) string {
+	_, scriptPromptFormat := c.promptFormats()
+	return c.renderPrompt(scriptPromptFormat, map[string]string{"script": scriptname})
+}
+
+// A field of the prompt templates with its description
+type promptFieldInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// The templates of the prompts and the fields available
+type promptStatus struct {
+	Prompt       string            `json:"prompt"`
+	ScriptPrompt string            `json:"scriptPrompt"`
+	Fields       []promptFieldInfo `json:"fields"`
+}
+
+func (s promptStatus) String() string {
+
+	width := 0
+	for _, field := range s.Fields {
+		if len(field.Name) > width {
+			width = len(field.Name)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("prompt: %q\nscript prompt: %q\nfields:", s.Prompt, s.ScriptPrompt))
+	for _, field := range s.Fields {
+		sb.WriteString(fmt.Sprintf("\n  %-*s  %s", width, field.Name, field.Description))
+	}
+	return sb.String()
+}
+
+func (c *Commander) promptCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	script := len(arguments) > 0 && arguments[0] == "-script"
+	if script {
+		arguments = arguments[1:]
+	}
+
+	// Show the templates and the fields
+	if len(arguments) == 0 {
+		if script {
+			return nil, fmt.Errorf("wrong input. Usage: \n\t 'prompt [-script] [template]'")
+		}
+		status := promptStatus{Fields: []promptFieldInfo{}}
+		status.Prompt, status.ScriptPrompt = c.promptFormats()
+		for _, name := range c.promptFieldNames() {
+			field, _ := c.lookupPromptField(name)
+			status.Fields = append(status.Fields, promptFieldInfo{Name: "{" + name + "}", Description: field.Description})
+		}
+		return status, nil
+	}
+
+	template := strings.Join(arguments, " ")
+	err := c.checkPromptTemplate(template)
+	if _, unknown := err.(*unknownFieldError); unknown && executionOf(ctx).shared {
+		c.logs.Info("Prompt with fields of another tool skipped", "template", template)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.stateMutex.Lock()
+	if script {
+		c.scriptPromptFormat = template
+	} else {
+		c.promptFormat = template
+	}
+	c.stateMutex.Unlock()
+
+	SetResult(ctx, template)
+	return nil, nil
+}
+
+// Completer of the prompt command, which completes the fields
+func (c *Commander) promptCompleter(arguments []string) []string {
+
+	last := arguments[len(arguments)-1]
+	if len(arguments) == 1 && strings.HasPrefix(last, "-") {
+		return CompleteWords(last, "-script")
+	}
+
+	// Complete the field opened last, e.g. '< {ti' to '< {time}'
+	start := strings.LastIndexByte(last, '{')
+	if start < 0 || strings.IndexByte(last[start:], '}') >= 0 {
+		return nil
+	}
+	var completions []string
+	for _, name := range c.promptFieldNames() {
+		if strings.HasPrefix(name, last[start+1:]) {
+			completions = append(completions, last[:start+1]+name+"}")
+		}
+	}
+	return completions
+}
@@ -0,0 +1,80 @@
+package commander
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestParsePromptTemplate(t *testing.T) {
+
+	segments, err := parsePromptTemplate("< {time} {name}> ")
+	if err != nil {
+		t.Fatalf("parsePromptTemplate(): unexpected error: %v", err)
+	}
+	want := []promptSegment{{"< ", false}, {"time", true}, {" ", false}, {"name", true}, {"> ", false}}
+	if len(segments) != len(want) {
+		t.Fatalf("parsePromptTemplate() = %v, want %v", segments, want)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("parsePromptTemplate()[%d] = %v, want %v", i, segments[i], want[i])
+		}
+	}
+
+	for _, template := range []string{"{time", "{}", "{a b}", "{1}"} {
+		if _, err := parsePromptTemplate(template); err == nil {
+			t.Errorf("parsePromptTemplate(%q): expected error", template)
+		}
+	}
+}
+
+func TestPrompt(t *testing.T) {
+
+	c, out := newTestCommander("")
+	c.RegisterPromptField(PromptField{Name: "peers", Value: func(ctx context.Context) (string, error) {
+		return "3", nil
+	}})
+	c.RegisterPromptField(PromptField{Name: "peer", Value: func(ctx context.Context) (string, error) {
+		return "", errors.New("daemon not running")
+	}})
+
+	tests := []struct {
+		line   string
+		wantOK bool
+		want   string
+	}{
+		{"prompt '[{name}] {peers} {peer} {status}> '", true, "[test] 3 ? 0> "},
+		{"prompt '{status}> '; sleep x", false, "1> "},
+		{"echo ok", true, "0> "},
+		{"prompt '{unknown}> '", false, "1> "},
+		{"prompt '{status'", false, "1> "},
+	}
+
+	for _, tt := range tests {
+		out.Reset()
+		if ok := c.executeCommand(tt.line); ok != tt.wantOK {
+			t.Errorf("executeCommand(%q) = %v, want %v, output %q", tt.line, ok, tt.wantOK, out.String())
+		}
+		if got := c.prompt(); got != tt.want {
+			t.Errorf("prompt() after %q = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	if !c.executeCommand("prompt -script '{script}:{name}> '") {
+		t.Fatalf("prompt -script failed: %q", out.String())
+	}
+	if got := c.scriptPrompt("a.cmd"); got != "a.cmd:test> " {
+		t.Errorf("scriptPrompt() = %q, want %q", got, "a.cmd:test> ")
+	}
+
+	out.Reset()
+	if !c.executeCommand("prompt") {
+		t.Fatalf("prompt failed: %q", out.String())
+	}
+	if !regexp.MustCompile(`(?m)^  \{peers\} *$`).MatchString(out.String()) ||
+		!regexp.MustCompile(`(?m)^  \{time\} +the current time`).MatchString(out.String()) {
+		t.Errorf("prompt printed %q, missing the fields", out.String())
+	}
+}
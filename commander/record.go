@@ -0,0 +1,164 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Layout of the timestamps of recorded command lines
+const recordLayout = "2006-01-02 15:04:05"
+
+// A recording of the successful command lines at the prompt as script
+type recorder struct {
+	file *os.File
+
+	// Whether the output is recorded as 'expect' lines
+	expect bool
+}
+
+// Record the command line at the prompt, which succeeded, with a timestamp and its output, if asked for
+//
+// The 'record' command itself is not recorded.
+func (c *Commander) recordCommandline(line string) error {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if c.recording == nil {
+		return nil
+	}
+	if tokens, err := tokenize(line, nil); err != nil || len(tokens) == 0 || tokens[0].value == "record" {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n# %s\n%s\n", time.Now().Format(recordLayout), strings.TrimSpace(line)))
+	if c.recording.expect {
+		for _, outputLine := range strings.Split(c.foreground.lastOutput, "\n") {
+			if len(strings.TrimSpace(outputLine)) > 0 {
+				sb.WriteString(fmt.Sprintf("expect %s\n", quoteLiteral("^"+regexp.QuoteMeta(outputLine)+"$")))
+			}
+		}
+	}
+
+	_, err := c.recording.file.WriteString(sb.String())
+	if err != nil {
+		return fmt.Errorf("record: %v", err)
+	}
+	return nil
+}
+
+// Quote the word to be taken literally by the lexer
+func quoteLiteral(word string) string {
+
+	if !strings.Contains(word, "'") {
+		return "'" + word + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(word) + `"`
+}
+
+// Start recording to the file, which is created or truncated, and stop a former recording
+func (c *Commander) startRecording(filename string, expect bool) error {
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("os.Create: %v", err)
+	}
+	_, err = fmt.Fprintf(file, "# Session %q recorded at %s\n", c.name, time.Now().Format(recordLayout))
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("record: %v", err)
+	}
+
+	err = c.stopRecording()
+
+	c.stateMutex.Lock()
+	c.recording = &recorder{file: file, expect: expect}
+	c.stateMutex.Unlock()
+
+	c.logs.Info("Start recording", "file", filename, "expect", expect)
+	return err
+}
+
+// Stop recording, if any
+func (c *Commander) stopRecording() error {
+
+	c.stateMutex.Lock()
+	recording := c.recording
+	c.recording = nil
+	c.stateMutex.Unlock()
+
+	if recording == nil {
+		return nil
+	}
+	c.logs.Info("Stop recording", "file", recording.file.Name())
+	return recording.file.Close()
+}
+
+// The file recorded to, if any
+type recordStatus struct {
+	File   string `json:"file"`
+	Expect bool   `json:"expect"`
+}
+
+func (s recordStatus) String() string {
+	if len(s.File) == 0 {
+		return "recording is off"
+	}
+	if s.Expect {
+		return fmt.Sprintf("recording to %s with output as expect lines", s.File)
+	}
+	return fmt.Sprintf("recording to %s", s.File)
+}
+
+func (c *Commander) recordCommand(ctx context.Context, arguments []string) (interface{}, error) {
+
+	switch {
+	case len(arguments) == 0:
+		c.stateMutex.Lock()
+		defer c.stateMutex.Unlock()
+
+		status := recordStatus{}
+		if c.recording != nil {
+			status = recordStatus{File: c.recording.file.Name(), Expect: c.recording.expect}
+		}
+		SetResult(ctx, status.File)
+		return status, nil
+
+	case arguments[0] == "on" && len(arguments) == 2:
+		return nil, c.startRecording(arguments[1], false)
+
+	case arguments[0] == "on" && len(arguments) == 3 && arguments[1] == "-expect":
+		return nil, c.startRecording(arguments[2], true)
+
+	case arguments[0] == "off" && len(arguments) == 1:
+		c.stateMutex.Lock()
+		recording := c.recording != nil
+		c.stateMutex.Unlock()
+		if !recording {
+			return nil, fmt.Errorf("recording is off")
+		}
+		return nil, c.stopRecording()
+
+	default:
+		return nil, fmt.Errorf("wrong input. Usage: \n\t 'record [(on [-expect] <file>)|off]'")
+	}
+}
+
+// Completer of the record command
+func recordCompleter(arguments []string) []string {
+
+	switch {
+	case len(arguments) == 1:
+		return CompleteWords(arguments[0], "on", "off")
+	case len(arguments) == 2 && arguments[0] == "on":
+		return append(CompleteWords(arguments[1], "-expect"), completeFiles(arguments[1])...)
+	case len(arguments) == 3 && arguments[0] == "on" && arguments[1] == "-expect":
+		return completeFiles(arguments[2])
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package commander
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuoteLiteral(t *testing.T) {
+
+	for _, word := range []string{"", "plain", "a b", `it's`, `"$x" \ 'y'`, "^\\[1\\] done$"} {
+		tokens, err := tokenize("expect "+quoteLiteral(word), func(string) string { return "substituted" })
+		if err != nil {
+			t.Errorf("tokenize(quoteLiteral(%q)): unexpected error: %v", word, err)
+			continue
+		}
+		if len(tokens) != 2 || tokens[1].value != word {
+			t.Errorf("tokenize(quoteLiteral(%q)) = %v, want the word", word, tokens)
+		}
+	}
+}
+
+// A recorded session replays as test
+func TestRecord(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-record")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	filename := filepath.Join(dir, "session.cmd")
+
+	c, out := newTestCommander("")
+	for _, line := range []string{
+		"record on -expect " + filename,
+		"set greeting 'it''s $1'",
+		"echo $greeting; echo [1] (done)",
+		"sleep x",
+		"record off",
+		"echo after",
+	} {
+		if c.executeCommand(line) {
+			err := c.recordCommandline(line)
+			if err != nil {
+				t.Fatalf("recordCommandline(%q): unexpected error: %v", line, err)
+			}
+		}
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): %v", err)
+	}
+	script := string(b)
+	for _, want := range []string{"\n# ", "\nset greeting 'it''s $1'\n", "\necho $greeting; echo [1] (done)\nexpect '^\\[1\\] \\(done\\)$'\n"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("recorded %q, missing %q", script, want)
+		}
+	}
+	for _, unwanted := range []string{"record", "sleep x", "after"} {
+		if strings.Contains(strings.SplitN(script, "\n", 2)[1], unwanted) {
+			t.Errorf("recorded %q, unexpected %q", script, unwanted)
+		}
+	}
+
+	out.Reset()
+	if !c.executeCommand("test " + filename) {
+		t.Errorf("test of recorded session failed: %q", out.String())
+	}
+}
@@ -0,0 +1,231 @@
+package commander
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout of the timestamp appended to the names of rotated files
+const rotationLayout = "20060102-150405.000000"
+
+// A log file, which is rotated when exceeding its maximum size or age
+//
+// Rotated files are renamed with a timestamp appended, compressed with gzip, if switched on, and removed when
+// exceeding the number of files to retain.
+type rotatingFile struct {
+	mu       sync.Mutex
+	filename string
+	maxSize  int64
+	maxAge   time.Duration
+	retain   int
+	compress bool
+
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	// Serializes the compression and removal of rotated files, which runs without holding mu
+	cleanUpMu sync.Mutex
+}
+
+// Open the log file for appending, a maximum size or age of 0 means unlimited
+func openRotatingFile(filename string, maxSize int64, maxAge time.Duration, retain int, compress bool) (*rotatingFile, error) {
+
+	f := &rotatingFile{
+		filename: filename,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		retain:   retain,
+		compress: compress,
+	}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Open the file and take its size, its age starts with the opening
+func (f *rotatingFile) open() error {
+
+	file, err := os.OpenFile(f.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("error opening logfile %v: %v", f.filename, err)
+	}
+	f.file = file
+	f.size = 0
+	f.opened = time.Now()
+
+	if fileInfo, err := file.Stat(); err == nil && fileInfo.Mode().IsRegular() {
+		f.size = fileInfo.Size()
+	}
+	return nil
+}
+
+// Name returns the name of the file
+func (f *rotatingFile) Name() string {
+	return f.filename
+}
+
+// Write appends to the file, which is rotated before, if it would exceed its maximum size or age
+//
+// The rotated file is compressed and expired ones are removed after writing, without blocking other writes.
+// A failed rotation is returned after writing to the file kept open.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+
+	f.mu.Lock()
+	if f.file == nil {
+		f.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+
+	var rotated string
+	var rotateErr error
+	if f.size > 0 && ((f.maxSize > 0 && f.size+int64(len(p)) > f.maxSize) ||
+		(f.maxAge > 0 && time.Since(f.opened) > f.maxAge)) {
+		rotated, rotateErr = f.rotate()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	f.mu.Unlock()
+
+	if len(rotated) > 0 {
+		if cleanUpErr := f.cleanUp(rotated); rotateErr == nil {
+			rotateErr = cleanUpErr
+		}
+	}
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Close closes the file
+func (f *rotatingFile) Close() error {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Rename the file with a timestamp, reopen it and return the name of the rotated file, if renamed
+//
+// On errors the file stays open, i.e. the old one, if it cannot be reopened. Size and age start again to retry later.
+func (f *rotatingFile) rotate() (string, error) {
+
+	// Only regular files can be rotated, e.g. not /dev/null
+	if fileInfo, err := f.file.Stat(); err != nil || !fileInfo.Mode().IsRegular() {
+		f.size = 0
+		f.opened = time.Now()
+		return "", nil
+	}
+
+	rotated := f.filename + "." + time.Now().Format(rotationLayout)
+	err := os.Rename(f.filename, rotated)
+	if err != nil {
+		f.size = 0
+		f.opened = time.Now()
+		return "", fmt.Errorf("error rotating logfile %v: %v", f.filename, err)
+	}
+
+	old := f.file
+	err = f.open()
+	if err != nil {
+		f.size = 0
+		f.opened = time.Now()
+		return "", err
+	}
+	err = old.Close()
+	if err != nil {
+		return rotated, fmt.Errorf("error closing logfile %v: %v", rotated, err)
+	}
+	return rotated, nil
+}
+
+// Compress the rotated file, if switched on, and remove the oldest rotated files, one rotation after the other
+func (f *rotatingFile) cleanUp(rotated string) error {
+
+	f.cleanUpMu.Lock()
+	defer f.cleanUpMu.Unlock()
+
+	if f.compress {
+		err := compressFile(rotated)
+		if err != nil {
+			return err
+		}
+	}
+	return f.removeExpired()
+}
+
+// Remove the oldest rotated files exceeding the number of files to retain
+func (f *rotatingFile) removeExpired() error {
+
+	matches, err := filepath.Glob(f.filename + ".*")
+	if err != nil {
+		return fmt.Errorf("filepath.Glob: %v", err)
+	}
+
+	// The timestamps sort in chronological order
+	var rotated []string
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(match, f.filename+"."), ".gz")
+		if _, err := time.Parse(rotationLayout, suffix); err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+	sort.Strings(rotated)
+
+	for len(rotated) > f.retain {
+		err = os.Remove(rotated[0])
+		if err != nil {
+			return fmt.Errorf("error removing rotated logfile: %v", err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Compress the file with gzip into a file with the suffix ".gz" and remove the original
+func compressFile(filename string) error {
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error compressing logfile: %v", err)
+	}
+
+	out, err := os.OpenFile(filename+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		_ = in.Close()
+		return fmt.Errorf("error compressing logfile: %v", err)
+	}
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	_ = in.Close()
+	if err != nil {
+		_ = os.Remove(filename + ".gz")
+		return fmt.Errorf("error compressing logfile: %v", err)
+	}
+
+	return os.Remove(filename)
+}
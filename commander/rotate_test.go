@@ -0,0 +1,269 @@
+package commander
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Return a temporary directory and the function removing it
+func rotateTestDir(t *testing.T) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "cmdtool-rotate")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(): %v", err)
+	}
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// Write the lines to the file and fail on errors
+func writeLines(t *testing.T, f *rotatingFile, lines ...string) {
+
+	for _, line := range lines {
+		n, err := f.Write([]byte(line))
+		if err != nil || n != len(line) {
+			t.Fatalf("Write(%q) = %d, %v, want %d, nil", line, n, err, len(line))
+		}
+	}
+}
+
+// Return the rotated files of the file in chronological order
+func rotatedFiles(t *testing.T, filename string) []string {
+
+	matches, err := filepath.Glob(filename + ".*")
+	if err != nil {
+		t.Fatalf("filepath.Glob(): %v", err)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// Check the content of the file
+func checkContent(t *testing.T, filename, want string) {
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(): %v", err)
+	}
+	if string(b) != want {
+		t.Errorf("content of %s = %q, want %q", filepath.Base(filename), b, want)
+	}
+}
+
+func TestRotateSize(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	filename := filepath.Join(dir, "size.log")
+	f, err := openRotatingFile(filename, 10, 0, 5, false)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	defer f.Close()
+
+	writeLines(t, f, "first\n", "second\n", "third\n")
+
+	rotated := rotatedFiles(t, filename)
+	if len(rotated) != 2 {
+		t.Fatalf("rotated files = %q, want 2", rotated)
+	}
+	checkContent(t, rotated[0], "first\n")
+	checkContent(t, rotated[1], "second\n")
+	checkContent(t, filename, "third\n")
+}
+
+func TestRotateAge(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	filename := filepath.Join(dir, "age.log")
+	err := ioutil.WriteFile(filename, []byte("old\n"), 0666)
+	if err != nil {
+		t.Fatalf("ioutil.WriteFile(): %v", err)
+	}
+
+	// The age starts with the opening, not with the last modification
+	past := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(filename, past, past)
+	if err != nil {
+		t.Fatalf("os.Chtimes(): %v", err)
+	}
+	f, err := openRotatingFile(filename, 0, time.Hour, 5, false)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	defer f.Close()
+
+	writeLines(t, f, "first\n")
+	if rotated := rotatedFiles(t, filename); len(rotated) != 0 {
+		t.Fatalf("rotated files = %q, want none after opening", rotated)
+	}
+
+	f.opened = time.Now().Add(-2 * time.Hour)
+	writeLines(t, f, "second\n")
+
+	rotated := rotatedFiles(t, filename)
+	if len(rotated) != 1 {
+		t.Fatalf("rotated files = %q, want 1", rotated)
+	}
+	checkContent(t, rotated[0], "old\nfirst\n")
+	checkContent(t, filename, "second\n")
+}
+
+func TestRotateRetain(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	filename := filepath.Join(dir, "retain.log")
+	f, err := openRotatingFile(filename, 1, 0, 2, false)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	defer f.Close()
+
+	writeLines(t, f, "1\n", "2\n", "3\n", "4\n", "5\n")
+
+	rotated := rotatedFiles(t, filename)
+	if len(rotated) != 2 {
+		t.Fatalf("rotated files = %q, want 2", rotated)
+	}
+	checkContent(t, rotated[0], "3\n")
+	checkContent(t, rotated[1], "4\n")
+	checkContent(t, filename, "5\n")
+}
+
+func TestRotateCompress(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	filename := filepath.Join(dir, "compress.log")
+	f, err := openRotatingFile(filename, 10, 0, 5, true)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	defer f.Close()
+
+	writeLines(t, f, "first\n", "second\n")
+
+	rotated := rotatedFiles(t, filename)
+	if len(rotated) != 1 || filepath.Ext(rotated[0]) != ".gz" {
+		t.Fatalf("rotated files = %q, want 1 compressed", rotated)
+	}
+	file, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatalf("os.Open(): %v", err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip.NewReader(): %v", err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil || string(b) != "first\n" {
+		t.Errorf("uncompressed content = %q, %v, want %q", b, err, "first\n")
+	}
+	checkContent(t, filename, "second\n")
+}
+
+// A failed rotation keeps the file open for writing
+func TestRotateError(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	f, err := openRotatingFile(filepath.Join(dir, "error.log"), 10, 0, 5, false)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	defer f.Close()
+
+	writeLines(t, f, "first\n")
+	err = os.RemoveAll(dir)
+	if err != nil {
+		t.Fatalf("os.RemoveAll(): %v", err)
+	}
+
+	n, err := f.Write([]byte("second\n"))
+	if n != len("second\n") || err == nil || err == os.ErrClosed {
+		t.Errorf("Write() = %d, %v, want %d with the rotation error", n, err, len("second\n"))
+	}
+	if n, err := f.Write([]byte("3\n")); n != len("3\n") || err != nil {
+		t.Errorf("Write() after failed rotation = %d, %v, want %d, nil", n, err, len("3\n"))
+	}
+}
+
+// Compressing a rotated file does not block writes to the new one
+func TestRotateCleanUp(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	filename := filepath.Join(dir, "cleanup.log")
+	f, err := openRotatingFile(filename, 10, 0, 5, true)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	defer f.Close()
+
+	writeLines(t, f, "first\n")
+
+	// Hold the clean up of the rotation by the next write
+	f.cleanUpMu.Lock()
+	rotated := make(chan error)
+	go func() {
+		_, err := f.Write([]byte("second\n"))
+		rotated <- err
+	}()
+	for start := time.Now(); len(rotatedFiles(t, filename)) == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			f.cleanUpMu.Unlock()
+			t.Fatalf("no rotation by write of %q", "second\n")
+		}
+	}
+
+	written := make(chan error)
+	go func() {
+		_, err := f.Write([]byte("3\n"))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Errorf("Write() during clean up: unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Write() blocked by clean up")
+	}
+	f.cleanUpMu.Unlock()
+
+	if err := <-rotated; err != nil {
+		t.Errorf("Write() with rotation: unexpected error: %v", err)
+	}
+	checkContent(t, filename, "second\n3\n")
+}
+
+// The logger reports the first error of writing to its logfile
+func TestLoggerWriteError(t *testing.T) {
+
+	dir, remove := rotateTestDir(t)
+	defer remove()
+	f, err := openRotatingFile(filepath.Join(dir, "closed.log"), 0, 0, 5, false)
+	if err != nil {
+		t.Fatalf("openRotatingFile(): %v", err)
+	}
+	_ = f.Close()
+
+	errOut := &bytes.Buffer{}
+	l := &Logger{out: f, level: levelInfo, errOut: errOut}
+	l.Info("first")
+	l.Info("second")
+
+	if want := "error: logging: " + os.ErrClosed.Error() + "\n"; errOut.String() != want {
+		t.Errorf("logger reported %q, want %q", errOut.String(), want)
+	}
+}
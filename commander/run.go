@@ -0,0 +1,303 @@
+package commander
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/peterh/liner"
+)
+
+// Run parses the flags, starts the session with the built-in commands and the ones registered by the tool and runs
+// it interactively or in batch mode, until it exits the process with its status
+func Run(tool string, registerCommands func(c *Commander)) {
+
+	// nolog switches off logging
+	nolog := flag.Bool("nolog", false, "switches off logging")
+
+	// logfile is the file to write logging output, by default named after name and time
+	logfile := flag.String("logfile", "", "file to write logging output to, required for logging in batch mode")
+	flag.StringVar(logfile, "debugfile", "", "deprecated alias of -logfile")
+
+	// loglevel is the minimal level of the messages logged
+	loglevel := flag.String("loglevel", "info", "minimal level of logging: debug, info, warn or error")
+
+	// logformat is the format of the messages logged
+	logformat := flag.String("logformat", "text", "format of logging: text or json")
+
+	// logmaxsize, logmaxage, logretain and logcompress configure the rotation of logfiles
+	logmaxsize := flag.Int64("logmaxsize", 0, "rotates logfiles exceeding the size in megabytes, 0 for no limit")
+	logmaxage := flag.Duration("logmaxage", 0, "rotates logfiles exceeding the age, e.g. 24h, 0 for no limit")
+	logretain := flag.Int("logretain", 5, "number of rotated logfiles to keep")
+	logcompress := flag.Bool("logcompress", true, "compresses rotated logfiles with gzip")
+
+	// debug switches on logging of debug messages
+	debug := flag.Bool("debug", false, "switches on logging of debug messages, like '-loglevel debug'")
+
+	// historySize limits the history of command lines
+	historySize := flag.Int("histsize", 1000, "maximum number of command lines kept in the history")
+
+	// strictMode stops scripts at the first failing command
+	strictMode := flag.Bool("strict", false, "stops scripts at the first failing command, like 'set -e'")
+
+	// commandline and scriptfile are executed in batch mode
+	commandline := flag.String("c", "", "executes the commands in batch mode")
+	scriptfile := flag.String("f", "", "executes the script file in batch mode")
+
+	// testdir runs the test scripts in batch mode
+	testdir := flag.String("test", "", "runs the '*.cmd' scripts of the directory as tests in batch mode")
+
+	// sessionName is the name of the session, in batch mode instead of the first argument
+	sessionName := flag.String("name", "", "name of the session, in batch mode 'batch' by default")
+
+	// norc switches off the startup files
+	norc := flag.Bool("norc", false, "does not run the startup files ~/.cmdtoolrc and ./.cmdtoolrc.<name>")
+
+	// outputFormat is the format of the results of commands
+	outputFormat := flag.String("o", "plain", "format of the results of commands: plain, json, yaml or table")
+
+	// promptFormat is the template of the prompt
+	promptFormat := flag.String("prompt", defaultPromptFormat, "template of the prompt with fields like {time} and {name}, see 'prompt'")
+
+	// Parse input and set defaults of flags not given from the environment and the configuration files
+	flag.Parse()
+	batchMode := len(*commandline) > 0 || len(*scriptfile) > 0 || len(*testdir) > 0 || !stdinIsTerminal()
+	name, arguments := sessionArguments(batchMode, *sessionName, flag.Args())
+	sharedConfig, config := configFilenames(name)
+	err := applyConfig(flag.CommandLine, sharedConfig, config)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "wrong configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Run the tests like a command line
+	if len(*testdir) > 0 && len(*commandline) == 0 {
+		*commandline = "test " + strconv.Quote(*testdir)
+	}
+
+	// Check arguments
+	if len(name) == 0 || (len(*commandline) > 0 && len(*scriptfile) > 0) {
+		_, _ = fmt.Fprintf(os.Stderr, "missing or wrong parameter: <name>\n\n"+
+			"Usage: ./%[1]s [-nolog | -logfile <logfilename>] [-loglevel <level>] [-logformat text|json] [-o <format>] [-strict] <name>\n"+
+			"       ./%[1]s [-logfile <logfilename>] [-loglevel <level>] [-logformat text|json] [-o <format>] [-strict] "+
+			"[-name <name>] [-c <commands> | -f <scriptfile> | -test <dir>] [arguments]\n", tool)
+		os.Exit(1)
+	}
+
+	// Configure level and format of logging
+	level, err := parseLogLevel(*loglevel)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "wrong parameter -loglevel: %v\n", err)
+		os.Exit(1)
+	}
+	if *debug {
+		level = levelDebug
+	}
+	if *logformat != "text" && *logformat != "json" {
+		_, _ = fmt.Fprintf(os.Stderr, "wrong parameter -logformat: %q, use text or json\n", *logformat)
+		os.Exit(1)
+	}
+
+	// Check the format of the results
+	err = checkOutputFormat(*outputFormat)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "wrong parameter -o: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create the session on the standard input and output
+	c := New(name, os.Stdin, os.Stdout)
+	c.batchMode = batchMode
+	c.strictMode = *strictMode
+	c.outputFormat = *outputFormat
+	c.promptFormat = *promptFormat
+	c.historySize = *historySize
+	c.rotation = logRotation{maxSize: *logmaxsize * 1024 * 1024, maxAge: *logmaxage, retain: *logretain, compress: *logcompress}
+	c.logs.setLevel(level)
+	c.logs.setJSON(*logformat == "json")
+
+	// Route the standard logger used by other packages into the logger of the session
+	log.SetFlags(0)
+	log.SetOutput(stdLogWriter{c.logs})
+
+	// Start logging to file, unless switched off, in batch mode only to the file given
+	if !*nolog && (!batchMode || len(*logfile) > 0) {
+
+		sessionLogfile, err := c.startLogging(*logfile)
+		if err != nil {
+			panic(err)
+		}
+		defer sessionLogfile.Close()
+
+		// Current logfilename
+		if !batchMode {
+			fmt.Printf("Start logging to %q\n", sessionLogfile.Name())
+		}
+
+		// First entry in the logfile
+		c.logs.Info("Session starting", "tool", tool, "name", name, "loglevel", level)
+	}
+
+	// Initialize the commands of the tool and the aliases
+	registerCommands(c)
+	err = c.checkPromptTemplate(*promptFormat)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "wrong parameter -prompt: %v\n", err)
+		os.Exit(1)
+	}
+	err = c.aliasesInit(tool)
+	if err != nil {
+		fmt.Printf("error: aliases: %v\n", err)
+	}
+
+	// Run the startup files
+	if !*norc {
+		c.runStartupFiles()
+	}
+
+	if batchMode {
+
+		// Run the commands of the flags or of the standard input
+		err = c.runBatch(*commandline, *scriptfile, arguments)
+		if err != nil {
+			if err != errReported {
+				fmt.Printf("error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+	} else {
+
+		// Start loop with history and completion
+		err = c.interactiveLoop()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Exit with the status of the last command
+	if status := c.exitStatus(); status != 0 {
+		os.Exit(status)
+	}
+}
+
+// Return the name of the session and the arguments of the script in batch mode
+//
+// In batch mode all arguments are passed to the script and the name is given by the flag only, otherwise the first
+// argument is the name, unless given by the flag.
+func sessionArguments(batchMode bool, name string, args []string) (string, []string) {
+
+	if batchMode {
+		if len(name) == 0 {
+			name = "batch"
+		}
+		return name, args
+	}
+	if len(name) == 0 && len(args) > 0 {
+		name = args[0]
+	}
+	return name, nil
+}
+
+// Check, if the standard input is a terminal
+func stdinIsTerminal() bool {
+	fileInfo, err := os.Stdin.Stat()
+	return err == nil && fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// Run the commands of the command line, the script file or the input of the session as script
+//
+// The arguments are the parameters of the script.
+func (c *Commander) runBatch(commandline, scriptfile string, arguments []string) error {
+
+	ctx := withExecution(context.Background(), c.foreground)
+	if len(scriptfile) > 0 {
+		return c.runScriptFile(ctx, scriptfile, append([]string{scriptfile}, arguments...), false, false)
+	}
+
+	filename, content := "-c", commandline
+	if len(commandline) == 0 {
+		b, err := ioutil.ReadAll(c.in)
+		if err != nil {
+			return fmt.Errorf("ioutil.ReadAll: %v", err)
+		}
+		filename, content = "<stdin>", string(b)
+	}
+
+	// Parse the whole script to report syntax errors before anything runs
+	s, err := parseScript(filename, content)
+	if err != nil {
+		return err
+	}
+	return c.runScript(ctx, s, append([]string{filename}, arguments...), false)
+}
+
+func (c *Commander) interactiveLoop() error {
+
+	// Load the history of former sessions
+	err := c.historyInit()
+	if err != nil {
+		_, _ = fmt.Fprintf(c.out, "error: history: %v\n", err)
+	}
+
+	// Interrupts cancel the running command, at the prompt a second one in a row exits
+	c.handleInterrupts()
+
+	s := liner.NewLiner()
+	s.SetTabCompletionStyle(liner.TabPrints)
+	s.SetCompleter(c.completeCommand)
+	s.SetCtrlCAborts(true)
+	defer s.Close()
+	defer func() {
+		_ = c.stopRecording()
+	}()
+	for _, line := range c.historyLines() {
+		s.AppendHistory(line)
+	}
+	for {
+
+		// Report the background jobs done meanwhile
+		c.notifyJobs()
+
+		//noinspection GoUnresolvedReference
+		p, err := s.Prompt(c.prompt())
+		if err == io.EOF {
+			return nil
+		}
+		if err == liner.ErrPromptAborted {
+			if c.interruptPrompt() {
+				return nil
+			}
+			continue
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		// Expand references to the history like '!n'
+		p, expanded, err := c.expandHistory(p)
+		if err != nil {
+			_, _ = fmt.Fprintf(c.out, "error: %v\n", err)
+			continue
+		}
+		if expanded {
+			_, _ = fmt.Fprintf(c.out, "%s\n", p)
+		}
+
+		if c.executeCommand(p) {
+			s.AppendHistory(p)
+			err = c.addHistory(p)
+			if err != nil {
+				_, _ = fmt.Fprintf(c.out, "error: history: %v\n", err)
+			}
+			err = c.recordCommandline(p)
+			if err != nil {
+				_, _ = fmt.Fprintf(c.out, "error: %v\n", err)
+			}
+		}
+	}
+}
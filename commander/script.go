@@ -0,0 +1,469 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A node of the syntax tree of a script
+type scriptNode interface {
+	lineNumber() int
+}
+
+// A command line to execute
+type commandNode struct {
+	lineno int
+	line   string
+}
+
+// 'if <command line>' with the blocks executed on success or failure
+type ifNode struct {
+	lineno    int
+	line      string
+	condition string
+	then      []scriptNode
+	otherwise []scriptNode
+}
+
+// 'for <name> in <items>' with the block executed for each item
+type forNode struct {
+	lineno   int
+	line     string
+	variable string
+	items    string
+	body     []scriptNode
+}
+
+// 'repeat <count>' with the block executed count times
+type repeatNode struct {
+	lineno int
+	line   string
+	count  string
+	body   []scriptNode
+}
+
+// 'func <name>' with the block executed when the function is called
+type funcNode struct {
+	lineno int
+	line   string
+	name   string
+	body   []scriptNode
+}
+
+func (n *commandNode) lineNumber() int { return n.lineno }
+func (n *funcNode) lineNumber() int    { return n.lineno }
+func (n *ifNode) lineNumber() int      { return n.lineno }
+func (n *forNode) lineNumber() int     { return n.lineno }
+func (n *repeatNode) lineNumber() int  { return n.lineno }
+
+// Maximum depth of nested scripts and function calls
+const maxScriptDepth = 32
+
+// A script or function in execution with its current line
+type scriptFrame struct {
+	filename string
+	function string
+	lineno   int
+}
+
+// Push a frame onto the stack of scripts and functions in execution and return a function popping it
+func pushFrame(ctx context.Context, frame *scriptFrame) (func(), error) {
+
+	e := executionOf(ctx)
+	if len(e.stack) >= maxScriptDepth {
+		return nil, fmt.Errorf("maximum depth of %d nested scripts and functions exceeded", maxScriptDepth)
+	}
+	e.stack = append(e.stack, frame)
+
+	return func() {
+		e.stack = e.stack[:len(e.stack)-1]
+	}, nil
+}
+
+// Print an error together with the stack of file:line locations of the scripts and functions in execution
+func scriptErrorf(ctx context.Context, format string, a ...interface{}) {
+
+	e := executionOf(ctx)
+	_, _ = fmt.Fprintf(e.out, "error: "+format+"\n", a...)
+	for i := len(e.stack) - 1; i >= 0; i-- {
+		frame := e.stack[i]
+		if frame.function != "" {
+			_, _ = fmt.Fprintf(e.out, "\tat %s:%d (%s)\n", frame.filename, frame.lineno, frame.function)
+		} else {
+			_, _ = fmt.Fprintf(e.out, "\tat %s:%d\n", frame.filename, frame.lineno)
+		}
+	}
+}
+
+// A parsed script, which echoes its lines with the script prompt, unless quiet
+type script struct {
+	filename string
+	nodes    []scriptNode
+	quiet    bool
+
+	// The commander running the script
+	commander *Commander
+}
+
+// An open block while parsing
+type scriptBlock struct {
+	node    scriptNode
+	keyword string
+	nodes   *[]scriptNode
+}
+
+// Parse the content of a script into its syntax tree
+func parseScript(filename, content string) (*script, error) {
+
+	s := &script{filename: filename}
+
+	errorf := func(lineno int, format string, a ...interface{}) error {
+		return fmt.Errorf("%s:%d: %s", filename, lineno, fmt.Sprintf(format, a...))
+	}
+
+	// The stack of open blocks, the bottom is the script itself
+	blocks := []*scriptBlock{{nodes: &s.nodes}}
+
+	for i, line := range strings.Split(content, "\n") {
+		lineno := i + 1
+
+		tokens, err := tokenize(line, nil)
+		if err != nil {
+			return nil, errorf(lineno, "%v", err)
+		}
+
+		// Skip empty lines and comments
+		if len(tokens) == 0 {
+			continue
+		}
+
+		current := blocks[len(blocks)-1]
+
+		keyword := ""
+		if tokens[0].kind == tokenWord && line[tokens[0].pos] != '"' && line[tokens[0].pos] != '\'' {
+			keyword = tokens[0].value
+		}
+		rest := ""
+		if len(tokens) > 1 {
+			rest = strings.TrimSpace(line[tokens[1].pos:])
+		}
+
+		switch keyword {
+		case "if":
+			if rest == "" {
+				return nil, errorf(lineno, "missing command after 'if'")
+			}
+			if _, err := splitCommandline(line, tokens[1:]); err != nil {
+				return nil, errorf(lineno, "%v", err)
+			}
+			node := &ifNode{lineno: lineno, line: line, condition: rest}
+			*current.nodes = append(*current.nodes, node)
+			blocks = append(blocks, &scriptBlock{node, keyword, &node.then})
+
+		case "else":
+			if len(tokens) > 1 {
+				return nil, errorf(lineno, "unexpected arguments after 'else'")
+			}
+			node, ok := current.node.(*ifNode)
+			if !ok || current.keyword != "if" {
+				return nil, errorf(lineno, "'else' without 'if'")
+			}
+			current.keyword = keyword
+			current.nodes = &node.otherwise
+
+		case "for":
+			if len(tokens) < 3 || tokens[1].kind != tokenWord || !isName(tokens[1].value) ||
+				tokens[2].kind != tokenWord || tokens[2].value != "in" {
+				return nil, errorf(lineno, "syntax error, expected 'for <name> in <items>'")
+			}
+			items := ""
+			if len(tokens) > 3 {
+				for _, t := range tokens[3:] {
+					if t.kind == tokenOperator {
+						return nil, errorf(lineno, "unexpected %q in items of 'for'", t.value)
+					}
+				}
+				items = line[tokens[3].pos:]
+			}
+			node := &forNode{lineno: lineno, line: line, variable: tokens[1].value, items: items}
+			*current.nodes = append(*current.nodes, node)
+			blocks = append(blocks, &scriptBlock{node, keyword, &node.body})
+
+		case "repeat":
+			if len(tokens) != 2 || tokens[1].kind != tokenWord {
+				return nil, errorf(lineno, "syntax error, expected 'repeat <count>'")
+			}
+
+			// Check literal counts already now
+			if !strings.Contains(tokens[1].value, "$") {
+				if n, err := strconv.Atoi(tokens[1].value); err != nil || n < 0 {
+					return nil, errorf(lineno, "invalid count %q of 'repeat'", tokens[1].value)
+				}
+			}
+			node := &repeatNode{lineno: lineno, line: line, count: rest}
+			*current.nodes = append(*current.nodes, node)
+			blocks = append(blocks, &scriptBlock{node, keyword, &node.body})
+
+		case "func":
+			if len(tokens) != 2 || tokens[1].kind != tokenWord || !isName(tokens[1].value) {
+				return nil, errorf(lineno, "syntax error, expected 'func <name>'")
+			}
+			node := &funcNode{lineno: lineno, line: line, name: tokens[1].value}
+			*current.nodes = append(*current.nodes, node)
+			blocks = append(blocks, &scriptBlock{node, keyword, &node.body})
+
+		case "end":
+			if len(tokens) > 1 {
+				return nil, errorf(lineno, "unexpected arguments after 'end'")
+			}
+			if len(blocks) == 1 {
+				return nil, errorf(lineno, "'end' without block")
+			}
+			blocks = blocks[:len(blocks)-1]
+
+		default:
+			if _, err := splitCommandline(line, tokens); err != nil {
+				return nil, errorf(lineno, "%v", err)
+			}
+			*current.nodes = append(*current.nodes, &commandNode{lineno: lineno, line: line})
+		}
+	}
+
+	if len(blocks) > 1 {
+		open := blocks[len(blocks)-1]
+		return nil, errorf(open.node.lineNumber(), "missing 'end' of %q", open.keyword)
+	}
+
+	return s, nil
+}
+
+// Execute the nodes and return the status of the last executed command
+//
+// In strict mode the execution stops at the first failure, a cancelled context stops it anyway.
+func (s *script) run(ctx context.Context, nodes []scriptNode) bool {
+
+	c := s.commander
+	stack := executionOf(ctx).stack
+	frame := stack[len(stack)-1]
+	report := func(err error) {
+		scriptErrorf(ctx, "%v", err)
+	}
+
+	ok := true
+	for _, node := range nodes {
+		if ctx.Err() != nil {
+			return false
+		}
+		frame.lineno = node.lineNumber()
+
+		switch n := node.(type) {
+
+		case *commandNode:
+			s.echo(ctx, n.line)
+			ok = c.executeChain(ctx, n.line, report)
+
+		case *ifNode:
+			s.echo(ctx, n.line)
+			e := executionOf(ctx)
+			condition := e.condition
+			e.condition = true
+			holds := c.executeChain(ctx, n.condition, report)
+			e.condition = condition
+			if holds {
+				ok = s.run(ctx, n.then)
+			} else {
+				ok = s.run(ctx, n.otherwise)
+			}
+
+		case *forNode:
+			s.echo(ctx, n.line)
+			items, err := splitWords(n.items, variableLookup(ctx))
+			if err != nil {
+				scriptErrorf(ctx, "%v", err)
+				ok = false
+				setStatus(ctx, ok)
+				continue
+			}
+			for _, item := range items {
+				c.putVariable(n.variable, item)
+				ok = s.run(ctx, n.body)
+				if (!ok && c.stopOnFailure(ctx)) || ctx.Err() != nil {
+					break
+				}
+			}
+
+		case *funcNode:
+			s.echo(ctx, n.line)
+			ok = s.define(ctx, n)
+			setStatus(ctx, ok)
+
+		case *repeatNode:
+			s.echo(ctx, n.line)
+			count, err := expandWord(ctx, n.count)
+			if err == nil {
+				var times int
+				times, err = strconv.Atoi(count)
+				if err == nil && times < 0 {
+					err = fmt.Errorf("negative count")
+				}
+				for i := 0; i < times && err == nil; i++ {
+					ok = s.run(ctx, n.body)
+					if (!ok && c.stopOnFailure(ctx)) || ctx.Err() != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				scriptErrorf(ctx, "invalid count %q of 'repeat': %v", count, err)
+				ok = false
+				setStatus(ctx, ok)
+			}
+		}
+
+		if !ok && c.stopOnFailure(ctx) {
+			return false
+		}
+	}
+	return ok
+}
+
+// Define the function as a command, which runs the body with the arguments as parameters
+func (s *script) define(ctx context.Context, n *funcNode) bool {
+
+	c := s.commander
+	c.stateMutex.Lock()
+	_, exists := c.commands[n.name]
+	builtin := exists && !c.functions[n.name]
+	if !builtin {
+		c.functions[n.name] = true
+	}
+	c.stateMutex.Unlock()
+
+	if builtin {
+		scriptErrorf(ctx, "cannot redefine command %q as function", n.name)
+		return false
+	}
+
+	c.Register(Command{
+		Name:        n.name,
+		Category:    CategoryFunctions,
+		Synopsis:    n.name + " [arguments]",
+		Description: fmt.Sprintf("Function defined in %s:%d.", s.filename, n.lineno),
+		Handler: func(ctx context.Context, arguments []string) (interface{}, error) {
+			pop, err := pushFrame(ctx, &scriptFrame{filename: s.filename, function: n.name, lineno: n.lineno})
+			if err != nil {
+				return nil, err
+			}
+			defer pop()
+
+			defer pushArguments(ctx, append([]string{n.name}, arguments...))()
+			if !s.run(ctx, n.body) {
+				return nil, errReported
+			}
+			return nil, nil
+		},
+	})
+
+	return true
+}
+
+// Echo the line of the script with the script prompt, but not in batch mode
+func (s *script) echo(ctx context.Context, line string) {
+	if s.commander.batchMode || s.quiet {
+		return
+	}
+	_, _ = fmt.Fprintf(Output(ctx), "%s%s\n", s.commander.scriptPrompt(s.filename), line)
+}
+
+// Substitute the variables of a single word
+func expandWord(ctx context.Context, word string) (string, error) {
+
+	tokens, err := tokenize(word, variableLookup(ctx))
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) != 1 {
+		return "", fmt.Errorf("%q is not a single word", word)
+	}
+	return tokens[0].value, nil
+}
+
+func (c *Commander) executeScript(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("no filename to execute specified")
+	}
+
+	return nil, c.runScriptFile(ctx, arguments[0], arguments, false, false)
+}
+
+func (c *Commander) sourceScript(ctx context.Context, arguments []string) (interface{}, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("no filename to source specified")
+	}
+
+	return nil, c.runScriptFile(ctx, arguments[0], arguments, true, false)
+}
+
+// Run the script file with the arguments as parameters
+//
+// An included file is resolved relative to the calling script, must not be in execution already and keeps the
+// parameters of the caller, if there are no arguments. Errors inside of the script are reported with the stack of
+// locations, i.e. errReported is returned, if the script fails. A quiet script does not echo its lines.
+func (c *Commander) runScriptFile(ctx context.Context, filename string, arguments []string, include, quiet bool) error {
+
+	stack := executionOf(ctx).stack
+	if include && len(stack) > 0 && !filepath.IsAbs(filename) {
+		filename = filepath.Join(filepath.Dir(stack[len(stack)-1].filename), filename)
+	}
+
+	// Detect include cycles
+	if include {
+		path, _ := filepath.Abs(filename)
+		for _, frame := range stack {
+			framePath, _ := filepath.Abs(frame.filename)
+			if frame.function == "" && framePath == path {
+				return fmt.Errorf("include cycle: %q is already in execution", filename)
+			}
+		}
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("ioutil.ReadFile: %v", err)
+	}
+
+	// Parse the whole script to report syntax errors before anything runs
+	s, err := parseScript(filename, string(b))
+	if err != nil {
+		return err
+	}
+	s.quiet = quiet
+
+	return c.runScript(ctx, s, arguments, include && len(arguments) == 1)
+}
+
+// Run the parsed script with the arguments as parameters, unless the ones of the caller are kept
+func (c *Commander) runScript(ctx context.Context, s *script, arguments []string, keepArguments bool) error {
+
+	s.commander = c
+	pop, err := pushFrame(ctx, &scriptFrame{filename: s.filename})
+	if err != nil {
+		return err
+	}
+	defer pop()
+
+	// Run with the script name and its arguments as parameters
+	if !keepArguments {
+		defer pushArguments(ctx, append([]string{s.filename}, arguments[1:]...))()
+	}
+	if !s.run(ctx, s.nodes) {
+		return errReported
+	}
+	return nil
+}
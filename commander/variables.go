@@ -0,0 +1,173 @@
+package commander
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Return the value of a variable, special variable or environment variable of the execution, or an empty string
+func lookupVariable(ctx context.Context, name string) string {
+
+	e := executionOf(ctx)
+
+	switch name {
+	case "_":
+		return e.result
+	case "?":
+		if e.succeeded() {
+			return "0"
+		}
+		return "1"
+	case "#":
+		if len(e.arguments) == 0 {
+			return "0"
+		}
+		return strconv.Itoa(len(e.arguments) - 1)
+	}
+
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 {
+		if n < len(e.arguments) {
+			return e.arguments[n]
+		}
+		return ""
+	}
+
+	if value, ok := e.commander.getVariable(name); ok {
+		return value
+	}
+	return os.Getenv(name)
+}
+
+// Return the lookup of variables of the execution for tokenize
+func variableLookup(ctx context.Context) func(name string) string {
+	return func(name string) string {
+		return lookupVariable(ctx, name)
+	}
+}
+
+// Return the value of a variable set by 'set'
+func (c *Commander) getVariable(name string) (string, bool) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	value, ok := c.variables[name]
+	return value, ok
+}
+
+// Set the value of a variable
+func (c *Commander) putVariable(name, value string) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	c.variables[name] = value
+}
+
+// Set the name and arguments of a script or function and return a function restoring the previous ones
+func pushArguments(ctx context.Context, arguments []string) func() {
+
+	e := executionOf(ctx)
+	previous := e.arguments
+	e.arguments = arguments
+
+	return func() {
+		e.arguments = previous
+	}
+}
+
+// SetResult sets the result of the current command, i.e. '$_' for the next one
+func SetResult(ctx context.Context, result string) {
+	executionOf(ctx).result = result
+}
+
+// Set the status of the last command, i.e. '$?'
+func setStatus(ctx context.Context, ok bool) {
+
+	e := executionOf(ctx)
+	e.commander.stateMutex.Lock()
+	defer e.commander.stateMutex.Unlock()
+
+	e.status = ok
+}
+
+// Variables by name
+type variableList map[string]string
+
+// Print the variables in sorted order
+func (l variableList) String() string {
+
+	var names []string
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s=%q", name, l[name])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *Commander) setVariable(ctx context.Context, arguments []string) (interface{}, error) {
+
+	// List all variables
+	if len(arguments) == 0 {
+		c.stateMutex.Lock()
+		defer c.stateMutex.Unlock()
+
+		list := make(variableList)
+		for name, value := range c.variables {
+			list[name] = value
+		}
+		return list, nil
+	}
+
+	// Switch strict mode of scripts
+	switch arguments[0] {
+	case "-e", "+e":
+		c.stateMutex.Lock()
+		c.strictMode = arguments[0] == "-e"
+		c.stateMutex.Unlock()
+		return nil, nil
+	}
+
+	if !isName(arguments[0]) {
+		return nil, fmt.Errorf("invalid variable name %q", arguments[0])
+	}
+
+	value := strings.Join(arguments[1:], " ")
+	c.putVariable(arguments[0], value)
+	SetResult(ctx, value)
+	return nil, nil
+}
+
+func (c *Commander) unsetVariable(ctx context.Context, arguments []string) (interface{}, error) {
+
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	for _, name := range arguments {
+		delete(c.variables, name)
+	}
+	return nil, nil
+}
+
+// Check for a valid variable name
+func isName(s string) bool {
+
+	if len(s) == 0 || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}